@@ -0,0 +1,25 @@
+package attribution
+
+import (
+	"time"
+
+	"tyrattribution/entity"
+)
+
+type firstClickModel struct{}
+
+func (m *firstClickModel) Name() string {
+	return ModelFirstClick
+}
+
+func (m *firstClickModel) Attribute(conversionDate time.Time, clicks []entity.ClickEvent) []Credit {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	sorted := sortedByClickDate(clicks)
+	weights := make([]float64, len(sorted))
+	weights[0] = 1
+
+	return creditsFromWeights(sorted, weights)
+}
@@ -0,0 +1,25 @@
+package attribution
+
+import (
+	"time"
+
+	"tyrattribution/entity"
+)
+
+type lastClickModel struct{}
+
+func (m *lastClickModel) Name() string {
+	return ModelLastClick
+}
+
+func (m *lastClickModel) Attribute(conversionDate time.Time, clicks []entity.ClickEvent) []Credit {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	sorted := sortedByClickDate(clicks)
+	weights := make([]float64, len(sorted))
+	weights[len(sorted)-1] = 1
+
+	return creditsFromWeights(sorted, weights)
+}
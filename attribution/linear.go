@@ -0,0 +1,27 @@
+package attribution
+
+import (
+	"time"
+
+	"tyrattribution/entity"
+)
+
+type linearModel struct{}
+
+func (m *linearModel) Name() string {
+	return ModelLinear
+}
+
+func (m *linearModel) Attribute(conversionDate time.Time, clicks []entity.ClickEvent) []Credit {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	sorted := sortedByClickDate(clicks)
+	weights := make([]float64, len(sorted))
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	return creditsFromWeights(sorted, weights)
+}
@@ -0,0 +1,102 @@
+package attribution
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"tyrattribution/entity"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ModelLastClick     = "last_click"
+	ModelFirstClick    = "first_click"
+	ModelLinear        = "linear"
+	ModelTimeDecay     = "time_decay"
+	ModelPositionBased = "position_based"
+)
+
+// Credit is the share of a single conversion assigned to one click.
+// The weights returned by a Model always sum to 1.
+type Credit struct {
+	ClickID    uuid.UUID
+	CampaignID uuid.UUID
+	Weight     float64
+}
+
+type Model interface {
+	Name() string
+	Attribute(conversionDate time.Time, clicks []entity.ClickEvent) []Credit
+}
+
+func NewModel(name string, halfLifeHours int) (Model, error) {
+	switch name {
+	case ModelLastClick:
+		return &lastClickModel{}, nil
+	case ModelFirstClick:
+		return &firstClickModel{}, nil
+	case ModelLinear:
+		return &linearModel{}, nil
+	case ModelTimeDecay:
+		if halfLifeHours <= 0 {
+			return nil, fmt.Errorf("time decay half-life must be positive, got %d hours", halfLifeHours)
+		}
+		return &timeDecayModel{halfLife: time.Duration(halfLifeHours) * time.Hour}, nil
+	case ModelPositionBased:
+		return &positionBasedModel{}, nil
+	default:
+		return nil, ValidateModelName(name)
+	}
+}
+
+func ValidateModelName(name string) error {
+	switch name {
+	case ModelLastClick, ModelFirstClick, ModelLinear, ModelTimeDecay, ModelPositionBased:
+		return nil
+	default:
+		return fmt.Errorf("unknown attribution model: %s", name)
+	}
+}
+
+// PrimaryCredit returns the credit with the highest weight, preferring the
+// most recent click on ties. It returns nil when there are no credits.
+func PrimaryCredit(credits []Credit) *Credit {
+	var primary *Credit
+	for i := range credits {
+		if primary == nil || credits[i].Weight >= primary.Weight {
+			primary = &credits[i]
+		}
+	}
+	return primary
+}
+
+func sortedByClickDate(clicks []entity.ClickEvent) []entity.ClickEvent {
+	sorted := make([]entity.ClickEvent, len(clicks))
+	copy(sorted, clicks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ClickDate.Before(sorted[j].ClickDate)
+	})
+	return sorted
+}
+
+func creditsFromWeights(clicks []entity.ClickEvent, weights []float64) []Credit {
+	var total float64
+	for _, weight := range weights {
+		total += weight
+	}
+	if total == 0 {
+		return nil
+	}
+
+	credits := make([]Credit, 0, len(clicks))
+	for i, click := range clicks {
+		credits = append(credits, Credit{
+			ClickID:    click.ClickID,
+			CampaignID: click.CampaignID,
+			Weight:     weights[i] / total,
+		})
+	}
+	return credits
+}
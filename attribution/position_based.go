@@ -0,0 +1,46 @@
+package attribution
+
+import (
+	"time"
+
+	"tyrattribution/entity"
+)
+
+const (
+	positionBasedEndpointWeight = 0.4
+	positionBasedMiddleWeight   = 0.2
+)
+
+// positionBasedModel is the U-shaped model: the first and last clicks get
+// 40% each and the remaining 20% is split evenly across the clicks between them.
+type positionBasedModel struct{}
+
+func (m *positionBasedModel) Name() string {
+	return ModelPositionBased
+}
+
+func (m *positionBasedModel) Attribute(conversionDate time.Time, clicks []entity.ClickEvent) []Credit {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	sorted := sortedByClickDate(clicks)
+	weights := make([]float64, len(sorted))
+
+	switch len(sorted) {
+	case 1:
+		weights[0] = 1
+	case 2:
+		weights[0] = 0.5
+		weights[1] = 0.5
+	default:
+		middleWeight := positionBasedMiddleWeight / float64(len(sorted)-2)
+		for i := range weights {
+			weights[i] = middleWeight
+		}
+		weights[0] = positionBasedEndpointWeight
+		weights[len(sorted)-1] = positionBasedEndpointWeight
+	}
+
+	return creditsFromWeights(sorted, weights)
+}
@@ -0,0 +1,36 @@
+package attribution
+
+import (
+	"math"
+	"time"
+
+	"tyrattribution/entity"
+)
+
+// timeDecayModel halves a click's weight for every half-life that passed
+// between the click and the conversion.
+type timeDecayModel struct {
+	halfLife time.Duration
+}
+
+func (m *timeDecayModel) Name() string {
+	return ModelTimeDecay
+}
+
+func (m *timeDecayModel) Attribute(conversionDate time.Time, clicks []entity.ClickEvent) []Credit {
+	if len(clicks) == 0 {
+		return nil
+	}
+
+	sorted := sortedByClickDate(clicks)
+	weights := make([]float64, len(sorted))
+	for i, click := range sorted {
+		age := conversionDate.Sub(click.ClickDate)
+		if age < 0 {
+			age = 0
+		}
+		weights[i] = math.Pow(0.5, age.Hours()/m.halfLife.Hours())
+	}
+
+	return creditsFromWeights(sorted, weights)
+}
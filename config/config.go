@@ -3,50 +3,158 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DBHost                    string
-	DBPort                    string
-	DBUser                    string
-	DBPassword                string
-	DBName                    string
-	DBSSLMode                 string
-	ClickEventTimeWindowHours int
-	REDISURL                  string
-	REDISPassword             string
-	REDISDBStr                string
-	KafkaUrl                  string
-	KafkaClickTopic           string
-	KafkaConversionTopic      string
+	DBHost                       string
+	DBPort                       string
+	DBUser                       string
+	DBPassword                   string
+	DBName                       string
+	DBSSLMode                    string
+	ClickEventTimeWindowHours    int
+	ClickEventMinDelaySeconds    int
+	ViewThroughWindowHours       int
+	AttributionModel             string
+	AttributionHalfLifeHours     int
+	AttributionCrossCampaign     bool
+	ConversionDedupWindowHours   int
+	CampaignSettingsCacheSeconds int
+	EventBatchMaxEvents          int
+	TrackedLinkAppendClickID     bool
+	TrackingCookieDomain         string
+	TrackingCookieSecure         bool
+	REDISURL                     string
+	REDISPassword                string
+	REDISDBStr                   string
+	EventTransport               string
+	MemoryBusBufferSize          int
+	RedisStreamMaxLen            int64
+	KafkaBrokers                 []string
+	KafkaProducerMode            string
+	KafkaProducerLingerMillis    int
+	KafkaProducerBatchSize       int
+	KafkaProducerCompression     string
+	KafkaProducerIdempotent      bool
+	KafkaClickTopic              string
+	KafkaConversionTopic         string
+	KafkaImpressionTopic         string
+	KafkaRetryTopic              string
+	KafkaDeadLetterTopic         string
+	ConsumerMaxRetries           int
+	ConsumerRetryBackoffSeconds  int
+	ConsumerMaxBackoffSeconds    int
+	ConsumerBatchSize            int
+	ConsumerBatchWaitMillis      int
+	OutboxEnabled                bool
+	OutboxRelayBatchSize         int
+	OutboxRelayIntervalMillis    int
+	OutboxRetentionHours         int
+	OutboxMaxAttempts            int
+	OutboxRetryBackoffSeconds    int
+	OutboxMaxBackoffSeconds      int
+	KafkaPartitionKey            string
+	ReattributionDelaySeconds    int
+	ReattributionIntervalSeconds int
+	ReattributionMaxDelaySeconds int
+	ReattributionBatchSize       int
+	KafkaClickConsumerGroup      string
+	KafkaConversionConsumerGroup string
+	KafkaImpressionConsumerGroup string
+	KafkaRetryConsumerGroup      string
+	KafkaDeadLetterConsumerGroup string
+	KafkaConsumerInitialOffset   string
+	KafkaRebalanceStrategy       string
+	KafkaPartitionWorkers        int
+	KafkaSASLMechanism           string
+	KafkaSASLUsername            string
+	KafkaSASLPassword            string
+	KafkaTLSEnabled              bool
+	KafkaTLSCAFile               string
+	KafkaTLSCertFile             string
+	KafkaTLSKeyFile              string
+	KafkaTLSSkipVerify           bool
+	CounterFlushIntervalSeconds  int
+	CounterFlushBatchSize        int
 }
 
 func LoadConfig() (*Config, error) {
 	godotenv.Load()
 
-	timeWindowHours := 24
-	if timeWindowStr := os.Getenv("CLICK_EVENT_TIME_WINDOW_HOURS"); timeWindowStr != "" {
-		if parsed, err := strconv.Atoi(timeWindowStr); err == nil {
-			timeWindowHours = parsed
-		}
-	}
-
 	return &Config{
-		DBHost:                    getEnv("DB_HOST", "localhost"),
-		DBPort:                    getEnv("DB_PORT", "5432"),
-		DBUser:                    getEnv("DB_USER", "postgres"),
-		DBPassword:                getEnv("DB_PASSWORD", ""),
-		DBName:                    getEnv("DB_NAME", "tyrattribution"),
-		DBSSLMode:                 getEnv("DB_SSL_MODE", "disable"),
-		ClickEventTimeWindowHours: timeWindowHours,
-		REDISURL:                  getEnv("REDIS_URL", "redis:6379"),
-		REDISPassword:             getEnv("REDIS_PASSWORD", ""),
-		REDISDBStr:                getEnv("REDIS_DB", "0"),
-		KafkaUrl:                  getEnv("KAFKA_BROKER_URL", "kafka:9092"),
-		KafkaClickTopic:           getEnv("KAFKA_CLICK_EVENT_TOPIC", "click_event"),
-		KafkaConversionTopic:      getEnv("KAFKA_CONVERSION_EVENT_TOPIC", "click_conversion"),
+		DBHost:                       getEnv("DB_HOST", "localhost"),
+		DBPort:                       getEnv("DB_PORT", "5432"),
+		DBUser:                       getEnv("DB_USER", "postgres"),
+		DBPassword:                   getEnv("DB_PASSWORD", ""),
+		DBName:                       getEnv("DB_NAME", "tyrattribution"),
+		DBSSLMode:                    getEnv("DB_SSL_MODE", "disable"),
+		ClickEventTimeWindowHours:    getEnvAsInt("CLICK_EVENT_TIME_WINDOW_HOURS", 24),
+		ClickEventMinDelaySeconds:    getEnvAsInt("CLICK_EVENT_MIN_DELAY_SECONDS", 0),
+		ViewThroughWindowHours:       getEnvAsInt("VIEW_THROUGH_WINDOW_HOURS", 0),
+		AttributionModel:             getEnv("ATTRIBUTION_MODEL", "last_click"),
+		AttributionHalfLifeHours:     getEnvAsInt("ATTRIBUTION_HALF_LIFE_HOURS", 168),
+		AttributionCrossCampaign:     getEnvAsBool("ATTRIBUTION_CROSS_CAMPAIGN", false),
+		ConversionDedupWindowHours:   getEnvAsInt("CONVERSION_DEDUP_WINDOW_HOURS", 720),
+		CampaignSettingsCacheSeconds: getEnvAsInt("CAMPAIGN_SETTINGS_CACHE_SECONDS", 60),
+		EventBatchMaxEvents:          getEnvAsInt("EVENT_BATCH_MAX_EVENTS", 10000),
+		TrackedLinkAppendClickID:     getEnvAsBool("TRACKED_LINK_APPEND_CLICK_ID", true),
+		TrackingCookieDomain:         getEnv("TRACKING_COOKIE_DOMAIN", ""),
+		TrackingCookieSecure:         getEnvAsBool("TRACKING_COOKIE_SECURE", false),
+		REDISURL:                     getEnv("REDIS_URL", "redis:6379"),
+		REDISPassword:                getEnv("REDIS_PASSWORD", ""),
+		REDISDBStr:                   getEnv("REDIS_DB", "0"),
+		EventTransport:               getEnv("EVENT_TRANSPORT", "kafka"),
+		MemoryBusBufferSize:          getEnvAsInt("MEMORY_BUS_BUFFER_SIZE", 10000),
+		RedisStreamMaxLen:            int64(getEnvAsInt("REDIS_STREAM_MAX_LEN", 1000000)),
+		KafkaBrokers:                 getEnvAsList("KAFKA_BROKER_URL", "kafka:9092"),
+		KafkaProducerMode:            getEnv("KAFKA_PRODUCER_MODE", "sync"),
+		KafkaProducerLingerMillis:    getEnvAsInt("KAFKA_PRODUCER_LINGER_MS", 5),
+		KafkaProducerBatchSize:       getEnvAsInt("KAFKA_PRODUCER_BATCH_SIZE", 1000),
+		KafkaProducerCompression:     getEnv("KAFKA_PRODUCER_COMPRESSION", "none"),
+		KafkaProducerIdempotent:      getEnvAsBool("KAFKA_PRODUCER_IDEMPOTENT", false),
+		KafkaClickTopic:              getEnv("KAFKA_CLICK_EVENT_TOPIC", "click_event"),
+		KafkaConversionTopic:         getEnv("KAFKA_CONVERSION_EVENT_TOPIC", "click_conversion"),
+		KafkaImpressionTopic:         getEnv("KAFKA_IMPRESSION_EVENT_TOPIC", "impression_event"),
+		KafkaRetryTopic:              getEnv("KAFKA_RETRY_TOPIC", "event_retry"),
+		KafkaDeadLetterTopic:         getEnv("KAFKA_DEAD_LETTER_TOPIC", "event_dead_letter"),
+		ConsumerMaxRetries:           getEnvAsInt("CONSUMER_MAX_RETRIES", 5),
+		ConsumerRetryBackoffSeconds:  getEnvAsInt("CONSUMER_RETRY_BACKOFF_SECONDS", 5),
+		ConsumerMaxBackoffSeconds:    getEnvAsInt("CONSUMER_MAX_BACKOFF_SECONDS", 300),
+		ConsumerBatchSize:            getEnvAsInt("CONSUMER_BATCH_SIZE", 500),
+		ConsumerBatchWaitMillis:      getEnvAsInt("CONSUMER_BATCH_WAIT_MS", 200),
+		OutboxEnabled:                getEnvAsBool("OUTBOX_ENABLED", false),
+		OutboxRelayBatchSize:         getEnvAsInt("OUTBOX_RELAY_BATCH_SIZE", 500),
+		OutboxRelayIntervalMillis:    getEnvAsInt("OUTBOX_RELAY_INTERVAL_MS", 500),
+		OutboxRetentionHours:         getEnvAsInt("OUTBOX_RETENTION_HOURS", 24),
+		OutboxMaxAttempts:            getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 25),
+		OutboxRetryBackoffSeconds:    getEnvAsInt("OUTBOX_RETRY_BACKOFF_SECONDS", 1),
+		OutboxMaxBackoffSeconds:      getEnvAsInt("OUTBOX_MAX_BACKOFF_SECONDS", 300),
+		KafkaPartitionKey:            getEnv("KAFKA_PARTITION_KEY", "user_id"),
+		ReattributionDelaySeconds:    getEnvAsInt("REATTRIBUTION_DELAY_SECONDS", 60),
+		ReattributionIntervalSeconds: getEnvAsInt("REATTRIBUTION_INTERVAL_SECONDS", 10),
+		ReattributionMaxDelaySeconds: getEnvAsInt("REATTRIBUTION_MAX_DELAY_SECONDS", 3600),
+		ReattributionBatchSize:       getEnvAsInt("REATTRIBUTION_BATCH_SIZE", 500),
+		KafkaClickConsumerGroup:      getEnv("KAFKA_CLICK_CONSUMER_GROUP", "tyr"),
+		KafkaConversionConsumerGroup: getEnv("KAFKA_CONVERSION_CONSUMER_GROUP", "tyr"),
+		KafkaImpressionConsumerGroup: getEnv("KAFKA_IMPRESSION_CONSUMER_GROUP", "tyr"),
+		KafkaRetryConsumerGroup:      getEnv("KAFKA_RETRY_CONSUMER_GROUP", "tyr-retry"),
+		KafkaDeadLetterConsumerGroup: getEnv("KAFKA_DEAD_LETTER_CONSUMER_GROUP", "tyr-dead-letter-redrive"),
+		KafkaConsumerInitialOffset:   getEnv("KAFKA_CONSUMER_INITIAL_OFFSET", "newest"),
+		KafkaRebalanceStrategy:       getEnv("KAFKA_REBALANCE_STRATEGY", "roundrobin"),
+		KafkaPartitionWorkers:        getEnvAsInt("KAFKA_PARTITION_WORKERS", 1),
+		KafkaSASLMechanism:           getEnv("KAFKA_SASL_MECHANISM", ""),
+		KafkaSASLUsername:            getEnv("KAFKA_SASL_USERNAME", ""),
+		KafkaSASLPassword:            getEnv("KAFKA_SASL_PASSWORD", ""),
+		KafkaTLSEnabled:              getEnvAsBool("KAFKA_TLS_ENABLED", false),
+		KafkaTLSCAFile:               getEnv("KAFKA_TLS_CA_FILE", ""),
+		KafkaTLSCertFile:             getEnv("KAFKA_TLS_CERT_FILE", ""),
+		KafkaTLSKeyFile:              getEnv("KAFKA_TLS_KEY_FILE", ""),
+		KafkaTLSSkipVerify:           getEnvAsBool("KAFKA_TLS_INSECURE_SKIP_VERIFY", false),
+		CounterFlushIntervalSeconds:  getEnvAsInt("COUNTER_FLUSH_INTERVAL_SECONDS", 5),
+		CounterFlushBatchSize:        getEnvAsInt("COUNTER_FLUSH_BATCH_SIZE", 1000),
 	}, nil
 }
 
@@ -56,3 +164,32 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvAsList splits a comma-separated value, dropping empty entries.
+func getEnvAsList(key, defaultValue string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, defaultValue), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getEnvAsInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,79 @@
+package consumer
+
+import (
+	"context"
+	"time"
+)
+
+// consumeBatches gathers items from the channel into micro-batches of up to
+// batchSize items, flushing a batch early once batchWait has passed since its
+// first item. It returns when the channel is closed, ctx is done or flush
+// fails. A batch cut short by ctx is dropped without flushing, leaving it to
+// the transport to deliver again.
+func consumeBatches[T any](ctx context.Context, items <-chan T, batchSize int, batchWait time.Duration, flush func(batch []T) error) error {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	batch := make([]T, 0, batchSize)
+	timer := time.NewTimer(batchWait)
+	timer.Stop()
+	defer timer.Stop()
+
+	commit := func() error {
+		timer.Stop()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := flush(batch); err != nil {
+			return err
+		}
+
+		batch = make([]T, 0, batchSize)
+		return nil
+	}
+
+	for {
+		select {
+		case item, ok := <-items:
+			if !ok {
+				return commit()
+			}
+
+			batch = append(batch, item)
+			if len(batch) == 1 {
+				timer.Reset(batchWait)
+			}
+
+			if len(batch) >= batchSize {
+				if err := commit(); err != nil {
+					return err
+				}
+			}
+
+		case <-timer.C:
+			if err := commit(); err != nil {
+				return err
+			}
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// processEach runs the deliveries of a failed batch one at a time, so a single
+// bad event is retried or dead-lettered on its own instead of failing its
+// whole batch.
+func processEach(deliveries []Delivery, process messageProcessor, failures *failureRouter) error {
+	for _, delivery := range deliveries {
+		if err := process(delivery.Value); err != nil {
+			if err := failures.route(newFailedMessage(delivery), err); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -5,104 +5,99 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 	"tyrattribution/config"
 
 	"tyrattribution/entity"
 	"tyrattribution/publisher"
 	"tyrattribution/service"
-
-	"github.com/IBM/sarama"
 )
 
 type ClickEventConsumer struct {
-	consumer sarama.ConsumerGroup
-	service  service.ClickEventService
-	topic    string
+	subscriber Subscriber
+	service    service.ClickEventService
+	failures   *failureRouter
+	topic      string
+	options    SubscribeOptions
 }
 
 type ClickEventMessage = publisher.ClickEvent
 
-func NewClickEventConsumer(cfg *config.Config, svc service.ClickEventService) (*ClickEventConsumer, error) {
-	brokerURL := cfg.KafkaUrl
-	topic := cfg.KafkaClickTopic
-	groupID := "tyr"
-
-	config := sarama.NewConfig()
-	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetNewest
-	config.Consumer.Return.Errors = true
-
-	consumer, err := sarama.NewConsumerGroup([]string{brokerURL}, groupID, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create consumer group: %w", err)
-	}
-
+func NewClickEventConsumer(cfg *config.Config, subscriber Subscriber, svc service.ClickEventService, failedMessagePub *publisher.FailedMessagePublisher) *ClickEventConsumer {
 	return &ClickEventConsumer{
-		consumer: consumer,
-		service:  svc,
-		topic:    topic,
-	}, nil
+		subscriber: subscriber,
+		service:    svc,
+		failures:   newFailureRouter(cfg, failedMessagePub),
+		topic:      cfg.KafkaClickTopic,
+		options: SubscribeOptions{
+			Group:      cfg.KafkaClickConsumerGroup,
+			BatchSize:  cfg.ConsumerBatchSize,
+			BatchWait:  time.Duration(cfg.ConsumerBatchWaitMillis) * time.Millisecond,
+			FromOldest: cfg.KafkaConsumerInitialOffset == "oldest",
+			Workers:    cfg.KafkaPartitionWorkers,
+		},
+	}
 }
 
 func (c *ClickEventConsumer) Start(ctx context.Context) error {
-	handler := &clickEventHandler{service: c.service}
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Click event consumer context cancelled")
-			return nil
-		case err := <-c.consumer.Errors():
-			log.Printf("Consumer error: %v", err)
-		default:
-			err := c.consumer.Consume(ctx, []string{c.topic}, handler)
-			if err != nil {
-				log.Printf("Error consuming messages: %v", err)
+	return c.subscriber.Subscribe(ctx, c.topic, c.options, c.flush)
+}
+
+// flush stores a micro-batch with one bulk write. Undecodable messages are
+// dead-lettered on their own; if the bulk write fails, the batch is processed
+// one message at a time so failures are retried individually.
+func (c *ClickEventConsumer) flush(ctx context.Context, deliveries []Delivery) error {
+	clickEvents := make([]*entity.ClickEvent, 0, len(deliveries))
+	decoded := make([]Delivery, 0, len(deliveries))
+
+	for _, delivery := range deliveries {
+		clickEvent, err := decodeClickEvent(delivery.Value)
+		if err != nil {
+			log.Printf("Error processing click event: %v", err)
+			if err := c.failures.route(newFailedMessage(delivery), err); err != nil {
+				log.Printf("Failed to route click event, leaving batch unacknowledged: %v", err)
 				return err
 			}
+			continue
 		}
-	}
-}
 
-func (c *ClickEventConsumer) Close() error {
-	return c.consumer.Close()
-}
+		clickEvents = append(clickEvents, clickEvent)
+		decoded = append(decoded, delivery)
+	}
 
-type clickEventHandler struct {
-	service service.ClickEventService
-}
+	if len(clickEvents) == 0 {
+		return nil
+	}
 
-func (h *clickEventHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
-func (h *clickEventHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+	if err := c.service.CreateClickEvents(context.Background(), clickEvents); err != nil {
+		log.Printf("Error saving batch of %d click events, processing one by one: %v", len(clickEvents), err)
+		return processEach(decoded, c.process, c.failures)
+	}
 
-func (h *clickEventHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for {
-		select {
-		case message := <-claim.Messages():
-			if message == nil {
-				return nil
-			}
+	return nil
+}
 
-			var eventMsg ClickEventMessage
-			if err := json.Unmarshal(message.Value, &eventMsg); err != nil {
-				log.Printf("Error unmarshaling message: %v", err)
-				session.MarkMessage(message, "")
-				continue
-			}
+// process decodes and stores one event. Decode failures are poison, anything
+// else is worth retrying.
+func (c *ClickEventConsumer) process(value []byte) error {
+	clickEvent, err := decodeClickEvent(value)
+	if err != nil {
+		return err
+	}
 
-			if err := h.saveClickEventToDB(eventMsg); err != nil {
-				log.Printf("Error saving click event to database: %v", err)
-			}
+	if err := c.service.CreateClickEvent(context.Background(), clickEvent); err != nil {
+		return fmt.Errorf("failed to save click event: %w", err)
+	}
 
-			session.MarkMessage(message, "")
+	return nil
+}
 
-		case <-session.Context().Done():
-			return nil
-		}
+func decodeClickEvent(value []byte) (*entity.ClickEvent, error) {
+	var eventMsg ClickEventMessage
+	if err := json.Unmarshal(value, &eventMsg); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal message: %v", errPoisonMessage, err)
 	}
-}
 
-func (h *clickEventHandler) saveClickEventToDB(eventMsg ClickEventMessage) error {
 	clickEvent := &entity.ClickEvent{
 		ClickID:    eventMsg.ClickID,
 		CampaignID: eventMsg.CampaignID,
@@ -112,21 +107,16 @@ func (h *clickEventHandler) saveClickEventToDB(eventMsg ClickEventMessage) error
 		CreatedAt:  eventMsg.CreatedAt,
 	}
 
-	return h.service.CreateClickEvent(context.Background(), clickEvent)
+	return clickEvent, nil
 }
 
-func StartClickEventConsumer(ctx context.Context, cfg *config.Config, svc service.ClickEventService) {
-	consumer, err := NewClickEventConsumer(cfg, svc)
-	if err != nil {
-		log.Fatalf("Failed to create click event consumer: %v", err)
-	}
+func StartClickEventConsumer(ctx context.Context, cfg *config.Config, subscriber Subscriber, svc service.ClickEventService, failedMessagePub *publisher.FailedMessagePublisher) {
+	consumer := NewClickEventConsumer(cfg, subscriber, svc, failedMessagePub)
 
 	log.Println("Starting click event consumer")
 	if err := consumer.Start(ctx); err != nil {
 		log.Printf("Click event consumer error: %v", err)
 	}
 
-	if err := consumer.Close(); err != nil {
-		log.Printf("Error closing click event consumer: %v", err)
-	}
+	log.Println("Click event consumer stopped")
 }
@@ -5,128 +5,122 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 	"tyrattribution/config"
+
 	"tyrattribution/entity"
 	"tyrattribution/publisher"
 	"tyrattribution/service"
-
-	"github.com/IBM/sarama"
 )
 
 type ConversionEventConsumer struct {
-	consumer sarama.ConsumerGroup
-	service  service.ConversionEventService
-	topic    string
+	subscriber Subscriber
+	service    service.ConversionEventService
+	failures   *failureRouter
+	topic      string
+	options    SubscribeOptions
 }
 
 type ConversionEventMessage = publisher.ConversionEvent
 
-func NewConversionEventConsumer(cfg *config.Config, svc service.ConversionEventService) (*ConversionEventConsumer, error) {
-	brokerURL := cfg.KafkaUrl
-	topic := cfg.KafkaConversionTopic
-	groupID := "tyr"
-
-	config := sarama.NewConfig()
-	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetNewest
-	config.Consumer.Return.Errors = true
-
-	consumer, err := sarama.NewConsumerGroup([]string{brokerURL}, groupID, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create consumer group: %w", err)
-	}
-
+func NewConversionEventConsumer(cfg *config.Config, subscriber Subscriber, svc service.ConversionEventService, failedMessagePub *publisher.FailedMessagePublisher) *ConversionEventConsumer {
 	return &ConversionEventConsumer{
-		consumer: consumer,
-		service:  svc,
-		topic:    topic,
-	}, nil
+		subscriber: subscriber,
+		service:    svc,
+		failures:   newFailureRouter(cfg, failedMessagePub),
+		topic:      cfg.KafkaConversionTopic,
+		options: SubscribeOptions{
+			Group:      cfg.KafkaConversionConsumerGroup,
+			BatchSize:  cfg.ConsumerBatchSize,
+			BatchWait:  time.Duration(cfg.ConsumerBatchWaitMillis) * time.Millisecond,
+			FromOldest: cfg.KafkaConsumerInitialOffset == "oldest",
+			Workers:    cfg.KafkaPartitionWorkers,
+		},
+	}
 }
 
 func (c *ConversionEventConsumer) Start(ctx context.Context) error {
-	handler := &conversionEventHandler{service: c.service}
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Conversion event consumer context cancelled")
-			return nil
-		case err := <-c.consumer.Errors():
-			log.Printf("Consumer error: %v", err)
-		default:
-			err := c.consumer.Consume(ctx, []string{c.topic}, handler)
-			if err != nil {
-				log.Printf("Error consuming messages: %v", err)
+	return c.subscriber.Subscribe(ctx, c.topic, c.options, c.flush)
+}
+
+// flush stores a micro-batch with one bulk write. Undecodable messages are
+// dead-lettered on their own; if the bulk write fails, the batch is processed
+// one message at a time so failures are retried individually.
+func (c *ConversionEventConsumer) flush(ctx context.Context, deliveries []Delivery) error {
+	conversionEvents := make([]*entity.ConversionEvent, 0, len(deliveries))
+	decoded := make([]Delivery, 0, len(deliveries))
+
+	for _, delivery := range deliveries {
+		conversionEvent, err := decodeConversionEvent(delivery.Value)
+		if err != nil {
+			log.Printf("Error processing conversion event: %v", err)
+			if err := c.failures.route(newFailedMessage(delivery), err); err != nil {
+				log.Printf("Failed to route conversion event, leaving batch unacknowledged: %v", err)
 				return err
 			}
+			continue
 		}
+
+		conversionEvents = append(conversionEvents, conversionEvent)
+		decoded = append(decoded, delivery)
 	}
-}
 
-func (c *ConversionEventConsumer) Close() error {
-	return c.consumer.Close()
-}
+	if len(conversionEvents) == 0 {
+		return nil
+	}
 
-type conversionEventHandler struct {
-	service service.ConversionEventService
-}
+	if err := c.service.CreateConversionEvents(context.Background(), conversionEvents); err != nil {
+		log.Printf("Error saving batch of %d conversion events, processing one by one: %v", len(conversionEvents), err)
+		return processEach(decoded, c.process, c.failures)
+	}
 
-func (h *conversionEventHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
-func (h *conversionEventHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+	return nil
+}
 
-func (h *conversionEventHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	for {
-		select {
-		case message := <-claim.Messages():
-			if message == nil {
-				return nil
-			}
+// process decodes and stores one event. Decode failures are poison, anything
+// else is worth retrying.
+func (c *ConversionEventConsumer) process(value []byte) error {
+	conversionEvent, err := decodeConversionEvent(value)
+	if err != nil {
+		return err
+	}
 
-			var eventMsg ConversionEventMessage
-			if err := json.Unmarshal(message.Value, &eventMsg); err != nil {
-				log.Printf("Error unmarshaling message: %v", err)
-				session.MarkMessage(message, "")
-				continue
-			}
+	if err := c.service.CreateConversionEvent(context.Background(), conversionEvent); err != nil {
+		return fmt.Errorf("failed to save conversion event: %w", err)
+	}
 
-			if err := h.saveConversionEventToDB(eventMsg); err != nil {
-				log.Printf("Error saving conversion event to database: %v", err)
-			}
-			session.MarkMessage(message, "")
+	return nil
+}
 
-		case <-session.Context().Done():
-			return nil
-		}
+func decodeConversionEvent(value []byte) (*entity.ConversionEvent, error) {
+	var eventMsg ConversionEventMessage
+	if err := json.Unmarshal(value, &eventMsg); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal message: %v", errPoisonMessage, err)
 	}
-}
 
-func (h *conversionEventHandler) saveConversionEventToDB(eventMsg ConversionEventMessage) error {
 	conversionEvent := &entity.ConversionEvent{
 		ConversionID:   eventMsg.ConversionID,
 		UserID:         eventMsg.UserID,
 		CampaignID:     eventMsg.CampaignID,
+		ClickID:        eventMsg.ClickID,
 		ConversionDate: eventMsg.ConversionDate,
 		Value:          eventMsg.Value,
 		Type:           eventMsg.Type,
 		Source:         eventMsg.Source,
+		OrderID:        eventMsg.OrderID,
 		CreatedAt:      eventMsg.CreatedAt,
 	}
 
-	return h.service.CreateConversionEvent(context.Background(), conversionEvent)
+	return conversionEvent, nil
 }
 
-func StartConversionEventConsumer(ctx context.Context, cfg *config.Config, svc service.ConversionEventService) {
-	consumer, err := NewConversionEventConsumer(cfg, svc)
-	if err != nil {
-		log.Fatalf("Failed to create conversion event consumer: %v", err)
-	}
+func StartConversionEventConsumer(ctx context.Context, cfg *config.Config, subscriber Subscriber, svc service.ConversionEventService, failedMessagePub *publisher.FailedMessagePublisher) {
+	consumer := NewConversionEventConsumer(cfg, subscriber, svc, failedMessagePub)
 
 	log.Println("Starting conversion event consumer")
 	if err := consumer.Start(ctx); err != nil {
 		log.Printf("Conversion event consumer error: %v", err)
 	}
 
-	if err := consumer.Close(); err != nil {
-		log.Printf("Error closing conversion event consumer: %v", err)
-	}
+	log.Println("Conversion event consumer stopped")
 }
@@ -0,0 +1,134 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"tyrattribution/config"
+	"tyrattribution/publisher"
+)
+
+// redriveIdleTimeout is how long the dead-letter topic may stay silent before
+// it is considered drained. It covers joining the consumer group as well.
+const redriveIdleTimeout = 10 * time.Second
+
+// errRedriveLimitReached rejects deliveries that arrive after the limit, so
+// they stay on the dead-letter topic for the next call.
+var errRedriveLimitReached = errors.New("redrive limit reached")
+
+// DeadLetterRedriver moves messages from the dead-letter topic back onto their
+// original topics on demand. It reads with its own subscriber group, so every
+// dead letter is re-driven at most once.
+type DeadLetterRedriver struct {
+	subscriber Subscriber
+	topic      string
+	group      string
+	publisher  *publisher.FailedMessagePublisher
+	mu         sync.Mutex
+}
+
+type RedriveResult struct {
+	Redriven int `json:"redriven"`
+	Skipped  int `json:"skipped"`
+}
+
+func NewDeadLetterRedriver(cfg *config.Config, subscriber Subscriber, failedMessagePub *publisher.FailedMessagePublisher) *DeadLetterRedriver {
+	return &DeadLetterRedriver{
+		subscriber: subscriber,
+		topic:      cfg.KafkaDeadLetterTopic,
+		group:      cfg.KafkaDeadLetterConsumerGroup,
+		publisher:  failedMessagePub,
+	}
+}
+
+// Redrive re-publishes up to limit dead letters, or all of them when limit is
+// zero, and returns once the limit is reached or the topic stays idle for
+// redriveIdleTimeout. Dead letters that cannot be decoded are skipped and
+// dropped.
+func (r *DeadLetterRedriver) Redrive(ctx context.Context, limit int) (*RedriveResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	activity := make(chan struct{}, 1)
+	go func() {
+		idle := time.NewTimer(redriveIdleTimeout)
+		defer idle.Stop()
+
+		for {
+			select {
+			case <-runCtx.Done():
+				return
+			case <-activity:
+				idle.Reset(redriveIdleTimeout)
+			case <-idle.C:
+				cancel()
+				return
+			}
+		}
+	}()
+
+	var resultMu sync.Mutex
+	var result RedriveResult
+	var redriveErr error
+
+	handler := func(ctx context.Context, deliveries []Delivery) error {
+		select {
+		case activity <- struct{}{}:
+		default:
+		}
+
+		resultMu.Lock()
+		defer resultMu.Unlock()
+
+		for _, delivery := range deliveries {
+			if redriveErr != nil {
+				return redriveErr
+			}
+			if limit > 0 && result.Redriven >= limit {
+				cancel()
+				return errRedriveLimitReached
+			}
+
+			var failed publisher.FailedMessage
+			if err := json.Unmarshal(delivery.Value, &failed); err != nil {
+				log.Printf("Skipping undecodable dead letter: %v", err)
+				result.Skipped++
+				continue
+			}
+
+			if err := r.publisher.Redrive(failed); err != nil {
+				redriveErr = fmt.Errorf("failed to redrive dead letter: %w", err)
+				cancel()
+				return redriveErr
+			}
+			result.Redriven++
+		}
+
+		if limit > 0 && result.Redriven >= limit {
+			cancel()
+		}
+		return nil
+	}
+
+	options := SubscribeOptions{Group: r.group, BatchSize: 1, FromOldest: true}
+	if err := r.subscriber.Subscribe(runCtx, r.topic, options, handler); err != nil {
+		return nil, fmt.Errorf("failed to consume dead letters: %w", err)
+	}
+
+	resultMu.Lock()
+	defer resultMu.Unlock()
+
+	if redriveErr != nil {
+		return &result, redriveErr
+	}
+
+	log.Printf("Re-drove %d dead letters, skipped %d", result.Redriven, result.Skipped)
+	return &result, nil
+}
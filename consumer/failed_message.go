@@ -0,0 +1,76 @@
+package consumer
+
+import (
+	"errors"
+	"time"
+	"tyrattribution/config"
+	"tyrattribution/publisher"
+)
+
+// errPoisonMessage marks failures that no retry can fix, such as a payload that
+// does not decode. Those messages go straight to the dead-letter topic.
+var errPoisonMessage = errors.New("poison message")
+
+// messageProcessor handles the payload of one message from an event topic. The
+// retry consumer uses it to run a failed message through its original handler.
+type messageProcessor func(value []byte) error
+
+// failureRouter decides where a message that failed processing goes next: the
+// retry topic with an exponential backoff, or the dead-letter topic once it is
+// poison or has used up its retries.
+type failureRouter struct {
+	publisher   *publisher.FailedMessagePublisher
+	maxRetries  int
+	baseBackoff time.Duration
+	maxBackoff  time.Duration
+}
+
+func newFailureRouter(cfg *config.Config, failedMessagePub *publisher.FailedMessagePublisher) *failureRouter {
+	return &failureRouter{
+		publisher:   failedMessagePub,
+		maxRetries:  cfg.ConsumerMaxRetries,
+		baseBackoff: time.Duration(cfg.ConsumerRetryBackoffSeconds) * time.Second,
+		maxBackoff:  time.Duration(cfg.ConsumerMaxBackoffSeconds) * time.Second,
+	}
+}
+
+// route publishes the failed message. Attempt is the number of times processing
+// has failed so far, so a message is retried at most maxRetries times. An error
+// means the message is neither retried nor dead-lettered and must not be marked
+// as consumed.
+func (r *failureRouter) route(failed publisher.FailedMessage, err error) error {
+	failed.Error = err.Error()
+	failed.FailedAt = time.Now()
+
+	if errors.Is(err, errPoisonMessage) || failed.Attempt > r.maxRetries {
+		return r.publisher.PublishDeadLetter(failed)
+	}
+
+	failed.RetryAt = failed.FailedAt.Add(r.backoff(failed.Attempt))
+	return r.publisher.PublishRetry(failed)
+}
+
+func (r *failureRouter) backoff(attempt int) time.Duration {
+	backoff := r.baseBackoff
+	for i := 1; i < attempt && backoff < r.maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > r.maxBackoff {
+		return r.maxBackoff
+	}
+	return backoff
+}
+
+// newFailedMessage captures a delivery from an event topic on its first failure.
+func newFailedMessage(delivery Delivery) publisher.FailedMessage {
+	return publisher.FailedMessage{
+		OriginalTopic: delivery.Topic,
+		Partition:     delivery.Partition,
+		Offset:        delivery.Offset,
+		MessageID:     delivery.MessageID,
+		Key:           delivery.Key,
+		Value:         delivery.Value,
+		Attempt:       1,
+	}
+}
@@ -0,0 +1,94 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+	"tyrattribution/config"
+
+	"tyrattribution/entity"
+	"tyrattribution/publisher"
+	"tyrattribution/service"
+)
+
+type ImpressionEventConsumer struct {
+	subscriber Subscriber
+	service    service.ImpressionEventService
+	failures   *failureRouter
+	topic      string
+	options    SubscribeOptions
+}
+
+type ImpressionEventMessage = publisher.ImpressionEvent
+
+func NewImpressionEventConsumer(cfg *config.Config, subscriber Subscriber, svc service.ImpressionEventService, failedMessagePub *publisher.FailedMessagePublisher) *ImpressionEventConsumer {
+	return &ImpressionEventConsumer{
+		subscriber: subscriber,
+		service:    svc,
+		failures:   newFailureRouter(cfg, failedMessagePub),
+		topic:      cfg.KafkaImpressionTopic,
+		options: SubscribeOptions{
+			Group:      cfg.KafkaImpressionConsumerGroup,
+			BatchSize:  1,
+			BatchWait:  time.Duration(cfg.ConsumerBatchWaitMillis) * time.Millisecond,
+			FromOldest: cfg.KafkaConsumerInitialOffset == "oldest",
+			Workers:    cfg.KafkaPartitionWorkers,
+		},
+	}
+}
+
+func (c *ImpressionEventConsumer) Start(ctx context.Context) error {
+	return c.subscriber.Subscribe(ctx, c.topic, c.options, c.flush)
+}
+
+// flush stores impressions one at a time; undecodable messages are
+// dead-lettered and failed writes retried.
+func (c *ImpressionEventConsumer) flush(ctx context.Context, deliveries []Delivery) error {
+	return processEach(deliveries, c.process, c.failures)
+}
+
+// process decodes and stores one event. Decode failures are poison, anything
+// else is worth retrying.
+func (c *ImpressionEventConsumer) process(value []byte) error {
+	impressionEvent, err := decodeImpressionEvent(value)
+	if err != nil {
+		return err
+	}
+
+	if err := c.service.CreateImpressionEvent(context.Background(), impressionEvent); err != nil {
+		return fmt.Errorf("failed to save impression event: %w", err)
+	}
+
+	return nil
+}
+
+func decodeImpressionEvent(value []byte) (*entity.ImpressionEvent, error) {
+	var eventMsg ImpressionEventMessage
+	if err := json.Unmarshal(value, &eventMsg); err != nil {
+		return nil, fmt.Errorf("%w: failed to unmarshal message: %v", errPoisonMessage, err)
+	}
+
+	impressionEvent := &entity.ImpressionEvent{
+		ImpressionID:   eventMsg.ImpressionID,
+		CampaignID:     eventMsg.CampaignID,
+		UserID:         eventMsg.UserID,
+		ImpressionDate: eventMsg.ImpressionDate,
+		Source:         eventMsg.Source,
+		CreatedAt:      eventMsg.CreatedAt,
+	}
+
+	return impressionEvent, nil
+}
+
+func StartImpressionEventConsumer(ctx context.Context, cfg *config.Config, subscriber Subscriber, svc service.ImpressionEventService, failedMessagePub *publisher.FailedMessagePublisher) {
+	consumer := NewImpressionEventConsumer(cfg, subscriber, svc, failedMessagePub)
+
+	log.Println("Starting impression event consumer")
+	if err := consumer.Start(ctx); err != nil {
+		log.Printf("Impression event consumer error: %v", err)
+	}
+
+	log.Println("Impression event consumer stopped")
+}
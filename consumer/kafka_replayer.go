@@ -0,0 +1,243 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"tyrattribution/config"
+	"tyrattribution/kafka"
+	"tyrattribution/publisher"
+	"tyrattribution/service"
+
+	"github.com/IBM/sarama"
+)
+
+// ReplayOptions selects the part of a topic to replay. Time bounds are matched
+// against message timestamps, and offset bounds apply to every partition. The
+// end bound is exclusive; without one the replay stops at the offsets current
+// when it starts.
+type ReplayOptions struct {
+	Topic string
+	// Group is the consumer group the replay commits its progress to. With
+	// Resume, a replay interrupted part way continues from the group's
+	// committed offsets; otherwise they are ignored and the range is replayed
+	// in full.
+	Group       string
+	Resume      bool
+	From        time.Time
+	To          time.Time
+	StartOffset int64
+	EndOffset   int64
+	BatchSize   int
+}
+
+// KafkaReplayer reads a bounded range of a topic partition by partition and
+// hands it to a batch handler, outside the live consumer groups.
+type KafkaReplayer struct {
+	cfg *config.Config
+}
+
+func NewKafkaReplayer(cfg *config.Config) *KafkaReplayer {
+	return &KafkaReplayer{
+		cfg: cfg,
+	}
+}
+
+// Replay runs handler over the selected range, partitions in parallel, and
+// returns how many messages were handled. It stops at the first batch the
+// handler rejects.
+func (r *KafkaReplayer) Replay(ctx context.Context, options ReplayOptions, handler BatchHandler) (int64, error) {
+	config, err := kafka.NewConfig(r.cfg)
+	if err != nil {
+		return 0, err
+	}
+	config.Consumer.Return.Errors = true
+
+	client, err := sarama.NewClient(r.cfg.KafkaBrokers, config)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create Kafka client: %w", err)
+	}
+	defer client.Close()
+
+	partitions, err := client.Partitions(options.Topic)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list partitions of %s: %w", options.Topic, err)
+	}
+
+	offsetManager, err := sarama.NewOffsetManagerFromClient(options.Group, client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create offset manager: %w", err)
+	}
+	defer offsetManager.Close()
+
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create Kafka consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	var replayed, skipped atomic.Int64
+	var wg sync.WaitGroup
+	errs := make([]error, len(partitions))
+
+	for i, partition := range partitions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.replayPartition(ctx, client, consumer, offsetManager, options, partition, handler, &replayed, &skipped)
+		}()
+	}
+
+	wg.Wait()
+
+	if int(skipped.Load()) == len(partitions) {
+		log.Printf("Warning: nothing to replay on any partition of %s, check the range", options.Topic)
+	}
+
+	return replayed.Load(), errors.Join(errs...)
+}
+
+func (r *KafkaReplayer) replayPartition(ctx context.Context, client sarama.Client, consumer sarama.Consumer, offsetManager sarama.OffsetManager, options ReplayOptions, partition int32, handler BatchHandler, replayed *atomic.Int64, skipped *atomic.Int64) error {
+	start, end, err := r.resolveRange(client, options, partition)
+	if err != nil {
+		return err
+	}
+
+	partitionOffsets, err := offsetManager.ManagePartition(options.Topic, partition)
+	if err != nil {
+		return fmt.Errorf("failed to manage offsets of partition %d: %w", partition, err)
+	}
+	defer partitionOffsets.Close()
+
+	if committed, _ := partitionOffsets.NextOffset(); options.Resume && committed > start && committed <= end {
+		log.Printf("Resuming replay of %s partition %d at committed offset %d", options.Topic, partition, committed)
+		start = committed
+	}
+
+	if start >= end {
+		log.Printf("Nothing to replay on %s partition %d", options.Topic, partition)
+		skipped.Add(1)
+		return nil
+	}
+
+	partitionConsumer, err := consumer.ConsumePartition(options.Topic, partition, start)
+	if err != nil {
+		return fmt.Errorf("failed to consume partition %d from offset %d: %w", partition, start, err)
+	}
+	defer partitionConsumer.Close()
+
+	log.Printf("Replaying %s partition %d, offsets %d to %d", options.Topic, partition, start, end)
+
+	// Forward messages until the end of the range, then close the channel so
+	// the last batch is flushed.
+	messages := make(chan *sarama.ConsumerMessage)
+	go func() {
+		defer close(messages)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-partitionConsumer.Messages():
+				if !ok || message.Offset >= end {
+					return
+				}
+				select {
+				case messages <- message:
+				case <-ctx.Done():
+					return
+				}
+				if message.Offset >= end-1 {
+					return
+				}
+			}
+		}
+	}()
+
+	err = consumeBatches(ctx, messages, options.BatchSize, time.Second, func(batch []*sarama.ConsumerMessage) error {
+		if err := handler(ctx, toDeliveries(batch)); err != nil {
+			return fmt.Errorf("replay of %s partition %d failed at offset %d: %w", options.Topic, partition, batch[0].Offset, err)
+		}
+
+		partitionOffsets.MarkOffset(batch[len(batch)-1].Offset+1, "")
+		replayed.Add(int64(len(batch)))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// resolveRange turns the replay bounds into the first offset to read and the
+// exclusive end offset of the partition.
+func (r *KafkaReplayer) resolveRange(client sarama.Client, options ReplayOptions, partition int32) (int64, int64, error) {
+	oldest, err := client.GetOffset(options.Topic, partition, sarama.OffsetOldest)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get oldest offset of partition %d: %w", partition, err)
+	}
+
+	newest, err := client.GetOffset(options.Topic, partition, sarama.OffsetNewest)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get newest offset of partition %d: %w", partition, err)
+	}
+
+	start := oldest
+	if options.StartOffset > start {
+		start = options.StartOffset
+	}
+	if !options.From.IsZero() {
+		start, err = offsetForTime(client, options.Topic, partition, options.From, newest)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	end := newest
+	if options.EndOffset >= 0 && options.EndOffset < end {
+		end = options.EndOffset
+	}
+	if !options.To.IsZero() {
+		end, err = offsetForTime(client, options.Topic, partition, options.To, newest)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return start, end, nil
+}
+
+// offsetForTime returns the offset of the first message at or after t, or
+// newest when there is none.
+func offsetForTime(client sarama.Client, topic string, partition int32, t time.Time, newest int64) (int64, error) {
+	offset, err := client.GetOffset(topic, partition, t.UnixMilli())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get offset of partition %d at %s: %w", partition, t.Format(time.RFC3339), err)
+	}
+
+	if offset < 0 {
+		return newest, nil
+	}
+
+	return offset, nil
+}
+
+// ReplayHandler returns the batch handler of the event consumer that reads
+// topic, so a replay runs through the same idempotent pipeline as live
+// traffic.
+func ReplayHandler(cfg *config.Config, topic string, clickEventService service.ClickEventService, conversionEventService service.ConversionEventService, impressionEventService service.ImpressionEventService, failedMessagePub *publisher.FailedMessagePublisher) (BatchHandler, error) {
+	switch topic {
+	case cfg.KafkaClickTopic:
+		return NewClickEventConsumer(cfg, nil, clickEventService, failedMessagePub).flush, nil
+	case cfg.KafkaConversionTopic:
+		return NewConversionEventConsumer(cfg, nil, conversionEventService, failedMessagePub).flush, nil
+	case cfg.KafkaImpressionTopic:
+		return NewImpressionEventConsumer(cfg, nil, impressionEventService, failedMessagePub).flush, nil
+	default:
+		return nil, fmt.Errorf("topic %s is not an event topic", topic)
+	}
+}
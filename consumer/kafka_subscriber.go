@@ -0,0 +1,253 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"tyrattribution/config"
+	"tyrattribution/kafka"
+
+	"github.com/IBM/sarama"
+)
+
+// KafkaSubscriber consumes topics through Kafka consumer groups. Each
+// Subscribe call joins its own group session, and offsets are marked only
+// after the handler accepts a batch.
+type KafkaSubscriber struct {
+	cfg *config.Config
+}
+
+func NewKafkaSubscriber(cfg *config.Config) (*KafkaSubscriber, error) {
+	s := &KafkaSubscriber{
+		cfg: cfg,
+	}
+
+	if cfg.KafkaConsumerInitialOffset != "newest" && cfg.KafkaConsumerInitialOffset != "oldest" {
+		return nil, fmt.Errorf("unknown Kafka initial offset %q, use newest or oldest", cfg.KafkaConsumerInitialOffset)
+	}
+
+	if _, err := s.newConfig(SubscribeOptions{}); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *KafkaSubscriber) newConfig(options SubscribeOptions) (*sarama.Config, error) {
+	config, err := kafka.NewConfig(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	switch s.cfg.KafkaRebalanceStrategy {
+	case "roundrobin":
+		config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+	case "range":
+		config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRange()}
+	case "sticky":
+		config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+	default:
+		return nil, fmt.Errorf("unknown Kafka rebalance strategy %q, use roundrobin, range or sticky", s.cfg.KafkaRebalanceStrategy)
+	}
+
+	config.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if options.FromOldest {
+		config.Consumer.Offsets.Initial = sarama.OffsetOldest
+	}
+	config.Consumer.Return.Errors = true
+
+	return config, nil
+}
+
+func (s *KafkaSubscriber) Subscribe(ctx context.Context, topic string, options SubscribeOptions, handler BatchHandler) error {
+	config, err := s.newConfig(options)
+	if err != nil {
+		return err
+	}
+
+	consumer, err := sarama.NewConsumerGroup(s.cfg.KafkaBrokers, options.Group, config)
+	if err != nil {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+	defer func() {
+		if err := consumer.Close(); err != nil {
+			log.Printf("Error closing consumer group for topic %s: %v", topic, err)
+		}
+	}()
+
+	groupHandler := &kafkaGroupHandler{options: options, handler: handler}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-consumer.Errors():
+			log.Printf("Consumer error: %v", err)
+		default:
+			if err := consumer.Consume(ctx, []string{topic}, groupHandler); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("error consuming messages: %w", err)
+			}
+		}
+	}
+}
+
+func (s *KafkaSubscriber) Close() error {
+	return nil
+}
+
+type kafkaGroupHandler struct {
+	options SubscribeOptions
+	handler BatchHandler
+}
+
+func (h *kafkaGroupHandler) Setup(sarama.ConsumerGroupSession) error   { return nil }
+func (h *kafkaGroupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *kafkaGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	if h.options.Workers > 1 {
+		return h.consumeWithWorkers(session, claim)
+	}
+
+	ctx := session.Context()
+
+	return consumeBatches(ctx, claim.Messages(), h.options.BatchSize, h.options.BatchWait, func(messages []*sarama.ConsumerMessage) error {
+		if err := h.handler(ctx, toDeliveries(messages)); err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			session.MarkMessage(message, "")
+		}
+		return nil
+	})
+}
+
+// consumeWithWorkers splits a partition's messages across workers by key, so
+// messages with the same key are still handled in order. An offset is marked
+// only once every earlier message of the partition has been handled.
+func (h *kafkaGroupHandler) consumeWithWorkers(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	ctx, cancel := context.WithCancel(session.Context())
+	defer cancel()
+
+	tracker := &offsetTracker{done: make(map[int64]bool)}
+	workers := make([]chan *sarama.ConsumerMessage, h.options.Workers)
+	errs := make(chan error, len(workers))
+	var wg sync.WaitGroup
+
+	for i := range workers {
+		workers[i] = make(chan *sarama.ConsumerMessage, h.options.BatchSize)
+		wg.Add(1)
+		go func(messages <-chan *sarama.ConsumerMessage) {
+			defer wg.Done()
+
+			err := consumeBatches(ctx, messages, h.options.BatchSize, h.options.BatchWait, func(batch []*sarama.ConsumerMessage) error {
+				if err := h.handler(ctx, toDeliveries(batch)); err != nil {
+					return err
+				}
+
+				if offset, ok := tracker.complete(batch); ok {
+					session.MarkOffset(claim.Topic(), claim.Partition(), offset, "")
+				}
+				return nil
+			})
+			if err != nil {
+				errs <- err
+				cancel()
+			}
+		}(workers[i])
+	}
+
+dispatch:
+	for {
+		select {
+		case <-ctx.Done():
+			break dispatch
+		case message, ok := <-claim.Messages():
+			if !ok {
+				break dispatch
+			}
+
+			tracker.add(message.Offset)
+			select {
+			case workers[workerIndex(message.Key, len(workers))] <- message:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+	}
+
+	for _, worker := range workers {
+		close(worker)
+	}
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+func workerIndex(key []byte, workers int) int {
+	hasher := fnv.New32a()
+	hasher.Write(key)
+	return int(hasher.Sum32() % uint32(workers))
+}
+
+// offsetTracker follows the messages of one partition that are being handled
+// out of order, and yields the offset to commit as the handled prefix grows.
+type offsetTracker struct {
+	mu      sync.Mutex
+	pending []int64
+	done    map[int64]bool
+}
+
+func (t *offsetTracker) add(offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.pending = append(t.pending, offset)
+}
+
+// complete records a handled batch and returns the next offset to commit when
+// every message before it has been handled.
+func (t *offsetTracker) complete(messages []*sarama.ConsumerMessage) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, message := range messages {
+		t.done[message.Offset] = true
+	}
+
+	var next int64
+	advanced := false
+	for len(t.pending) > 0 && t.done[t.pending[0]] {
+		next = t.pending[0] + 1
+		delete(t.done, t.pending[0])
+		t.pending = t.pending[1:]
+		advanced = true
+	}
+
+	return next, advanced
+}
+
+func toDeliveries(messages []*sarama.ConsumerMessage) []Delivery {
+	deliveries := make([]Delivery, len(messages))
+	for i, message := range messages {
+		deliveries[i] = Delivery{
+			Topic:     message.Topic,
+			Partition: message.Partition,
+			Offset:    message.Offset,
+			Key:       message.Key,
+			Value:     message.Value,
+		}
+	}
+
+	return deliveries
+}
@@ -0,0 +1,65 @@
+package consumer
+
+import (
+	"context"
+	"log"
+	"time"
+	"tyrattribution/publisher"
+)
+
+// memoryRetryDelay is how long a rejected batch waits before it is handed to
+// the handler again.
+const memoryRetryDelay = time.Second
+
+// MemorySubscriber consumes topics of an in-process MemoryBus. The bus has no
+// groups: subscribers of the same topic compete for its messages.
+type MemorySubscriber struct {
+	bus *publisher.MemoryBus
+}
+
+func NewMemorySubscriber(bus *publisher.MemoryBus) *MemorySubscriber {
+	return &MemorySubscriber{
+		bus: bus,
+	}
+}
+
+// Subscribe hands batches to the handler, retrying a rejected batch until it is
+// accepted. A batch still rejected when ctx is done goes back onto the bus.
+func (s *MemorySubscriber) Subscribe(ctx context.Context, topic string, options SubscribeOptions, handler BatchHandler) error {
+	messages := s.bus.Messages(topic)
+
+	return consumeBatches(ctx, messages, options.BatchSize, options.BatchWait, func(batch []publisher.Message) error {
+		deliveries := make([]Delivery, len(batch))
+		for i, message := range batch {
+			deliveries[i] = Delivery{
+				Topic: message.Topic,
+				Key:   message.Key,
+				Value: message.Value,
+			}
+		}
+
+		for {
+			err := handler(ctx, deliveries)
+			if err == nil {
+				return nil
+			}
+
+			log.Printf("Batch of %d messages from memory topic %s rejected, retrying: %v", len(batch), topic, err)
+
+			select {
+			case <-time.After(memoryRetryDelay):
+			case <-ctx.Done():
+				for _, message := range batch {
+					if err := s.bus.SendMessage(message); err != nil {
+						log.Printf("Dropping message from memory topic %s on shutdown: %v", topic, err)
+					}
+				}
+				return nil
+			}
+		}
+	})
+}
+
+func (s *MemorySubscriber) Close() error {
+	return nil
+}
@@ -0,0 +1,68 @@
+package consumer
+
+import (
+	"context"
+	"log"
+	"time"
+	"tyrattribution/config"
+	"tyrattribution/service"
+)
+
+// ReattributionWorker runs the delayed attribution pass for conversions that
+// were consumed before their click. Clicks and conversions are read by
+// separate consumers, so keying both topics by user does not order a user's
+// click ahead of their conversion on its own.
+type ReattributionWorker struct {
+	service   service.ConversionEventService
+	batchSize int
+	interval  time.Duration
+}
+
+func NewReattributionWorker(cfg *config.Config, svc service.ConversionEventService) *ReattributionWorker {
+	return &ReattributionWorker{
+		service:   svc,
+		batchSize: cfg.ReattributionBatchSize,
+		interval:  time.Duration(cfg.ReattributionIntervalSeconds) * time.Second,
+	}
+}
+
+// Start processes due conversions until the context is cancelled. A batch
+// that processed anything is followed straight away by the next one; a batch
+// that only failed waits for the interval.
+func (w *ReattributionWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		processed, err := w.service.ReattributePending(ctx, w.batchSize)
+		if err != nil {
+			log.Printf("Failed to reattribute pending conversions: %v", err)
+		}
+
+		if processed > 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Reattribution worker context cancelled")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func StartReattributionWorker(ctx context.Context, cfg *config.Config, svc service.ConversionEventService) {
+	if cfg.ReattributionDelaySeconds <= 0 {
+		log.Println("Delayed attribution disabled, reattribution worker not started")
+		return
+	}
+
+	worker := NewReattributionWorker(cfg, svc)
+
+	log.Println("Starting reattribution worker")
+	worker.Start(ctx)
+}
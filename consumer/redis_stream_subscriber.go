@@ -0,0 +1,168 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisStreamClaimInterval is how often entries left pending by crashed
+	// consumers, or by rejected batches, are claimed for another attempt.
+	redisStreamClaimInterval = 30 * time.Second
+	// redisStreamClaimMinIdle is how long an entry must stay pending before it
+	// can be claimed.
+	redisStreamClaimMinIdle = time.Minute
+	redisStreamRetryDelay   = time.Second
+	redisStreamMinBlock     = 100 * time.Millisecond
+)
+
+// RedisStreamSubscriber consumes Redis Streams through consumer groups.
+// Entries are acknowledged only after the handler accepts their batch.
+type RedisStreamSubscriber struct {
+	client       *goredis.Client
+	consumerName string
+}
+
+func NewRedisStreamSubscriber(client *goredis.Client) *RedisStreamSubscriber {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "tyr"
+	}
+
+	return &RedisStreamSubscriber{
+		client:       client,
+		consumerName: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Subscribe creates the group at the start of the stream if it does not exist,
+// so events published before the first consumer started are not skipped.
+func (s *RedisStreamSubscriber) Subscribe(ctx context.Context, topic string, options SubscribeOptions, handler BatchHandler) error {
+	err := s.client.XGroupCreateMkStream(ctx, topic, options.Group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	batchSize := int64(options.BatchSize)
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	block := options.BatchWait
+	if block < redisStreamMinBlock {
+		block = redisStreamMinBlock
+	}
+
+	// Entries this consumer read but never acknowledged before a restart are
+	// read first, then new entries.
+	readID := "0"
+	lastClaim := time.Now()
+
+	for ctx.Err() == nil {
+		var messages []goredis.XMessage
+
+		if time.Since(lastClaim) >= redisStreamClaimInterval {
+			messages, _, err = s.client.XAutoClaim(ctx, &goredis.XAutoClaimArgs{
+				Stream:   topic,
+				Group:    options.Group,
+				Consumer: s.consumerName,
+				MinIdle:  redisStreamClaimMinIdle,
+				Start:    "0-0",
+				Count:    batchSize,
+			}).Result()
+			lastClaim = time.Now()
+		} else {
+			var streams []goredis.XStream
+			streams, err = s.client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+				Group:    options.Group,
+				Consumer: s.consumerName,
+				Streams:  []string{topic, readID},
+				Count:    batchSize,
+				Block:    block,
+			}).Result()
+			if errors.Is(err, goredis.Nil) {
+				continue
+			}
+			if err == nil && len(streams) > 0 {
+				messages = streams[0].Messages
+			}
+			if err == nil && readID == "0" && len(messages) == 0 {
+				readID = ">"
+			}
+		}
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			log.Printf("Error reading stream %s: %v", topic, err)
+			s.wait(ctx)
+			continue
+		}
+
+		if len(messages) == 0 {
+			continue
+		}
+
+		ids := make([]string, len(messages))
+		deliveries := make([]Delivery, 0, len(messages))
+		for i, message := range messages {
+			ids[i] = message.ID
+			// Entries trimmed from the stream come back without values and
+			// are only acknowledged.
+			if len(message.Values) == 0 {
+				continue
+			}
+
+			deliveries = append(deliveries, Delivery{
+				Topic:     topic,
+				MessageID: message.ID,
+				Key:       streamValue(message.Values, "key"),
+				Value:     streamValue(message.Values, "value"),
+			})
+		}
+
+		if len(deliveries) > 0 {
+			if err := handler(ctx, deliveries); err != nil {
+				log.Printf("Batch of %d entries from stream %s rejected, leaving them pending: %v", len(deliveries), topic, err)
+				s.wait(ctx)
+				continue
+			}
+		}
+
+		// Acknowledge even when ctx is done, so an accepted batch is not
+		// delivered again.
+		if err := s.client.XAck(context.Background(), topic, options.Group, ids...).Err(); err != nil {
+			log.Printf("Failed to acknowledge %d entries from stream %s: %v", len(ids), topic, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *RedisStreamSubscriber) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStreamSubscriber) wait(ctx context.Context) {
+	select {
+	case <-time.After(redisStreamRetryDelay):
+	case <-ctx.Done():
+	}
+}
+
+func streamValue(values map[string]interface{}, field string) []byte {
+	value, ok := values[field].(string)
+	if !ok {
+		return nil
+	}
+
+	return []byte(value)
+}
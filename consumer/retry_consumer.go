@@ -0,0 +1,98 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+	"tyrattribution/config"
+	"tyrattribution/publisher"
+	"tyrattribution/service"
+)
+
+// RetryConsumer reprocesses messages from the retry topic once their backoff
+// has passed, using the handler of the topic they originally came from.
+type RetryConsumer struct {
+	subscriber Subscriber
+	processors map[string]messageProcessor
+	failures   *failureRouter
+	topic      string
+	group      string
+}
+
+func NewRetryConsumer(cfg *config.Config, subscriber Subscriber, processors map[string]messageProcessor, failedMessagePub *publisher.FailedMessagePublisher) *RetryConsumer {
+	return &RetryConsumer{
+		subscriber: subscriber,
+		processors: processors,
+		failures:   newFailureRouter(cfg, failedMessagePub),
+		topic:      cfg.KafkaRetryTopic,
+		group:      cfg.KafkaRetryConsumerGroup,
+	}
+}
+
+func (c *RetryConsumer) Start(ctx context.Context) error {
+	return c.subscriber.Subscribe(ctx, c.topic, SubscribeOptions{Group: c.group, BatchSize: 1}, c.handle)
+}
+
+// handle holds each message until its retry time. Retry times grow with the
+// attempt count, so a long backoff can delay messages behind it; they are
+// still retried in order, never early.
+func (c *RetryConsumer) handle(ctx context.Context, deliveries []Delivery) error {
+	for _, delivery := range deliveries {
+		var failed publisher.FailedMessage
+		if err := json.Unmarshal(delivery.Value, &failed); err != nil {
+			log.Printf("Error unmarshaling retry message: %v", err)
+			err = fmt.Errorf("%w: failed to unmarshal retry message: %v", errPoisonMessage, err)
+			if err := c.failures.route(newFailedMessage(delivery), err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if wait := time.Until(failed.RetryAt); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := c.retry(failed); err != nil {
+			log.Printf("Retry %d of message from %s failed: %v", failed.Attempt, failed.OriginalTopic, err)
+			failed.Attempt++
+			if err := c.failures.route(failed, err); err != nil {
+				log.Printf("Failed to route retried message, leaving it unacknowledged: %v", err)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *RetryConsumer) retry(failed publisher.FailedMessage) error {
+	process, ok := c.processors[failed.OriginalTopic]
+	if !ok {
+		return fmt.Errorf("%w: no handler for topic %s", errPoisonMessage, failed.OriginalTopic)
+	}
+
+	return process(failed.Value)
+}
+
+func StartRetryConsumer(ctx context.Context, cfg *config.Config, subscriber Subscriber, clickEventService service.ClickEventService, conversionEventService service.ConversionEventService, impressionEventService service.ImpressionEventService, failedMessagePub *publisher.FailedMessagePublisher) {
+	processors := map[string]messageProcessor{
+		cfg.KafkaClickTopic:      NewClickEventConsumer(cfg, subscriber, clickEventService, failedMessagePub).process,
+		cfg.KafkaConversionTopic: NewConversionEventConsumer(cfg, subscriber, conversionEventService, failedMessagePub).process,
+		cfg.KafkaImpressionTopic: NewImpressionEventConsumer(cfg, subscriber, impressionEventService, failedMessagePub).process,
+	}
+
+	consumer := NewRetryConsumer(cfg, subscriber, processors, failedMessagePub)
+
+	log.Println("Starting retry consumer")
+	if err := consumer.Start(ctx); err != nil {
+		log.Printf("Retry consumer error: %v", err)
+	}
+
+	log.Println("Retry consumer stopped")
+}
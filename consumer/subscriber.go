@@ -0,0 +1,44 @@
+package consumer
+
+import (
+	"context"
+	"time"
+)
+
+// Delivery is one message received from the event transport. Partition and
+// Offset are set by Kafka, MessageID by transports with their own message IDs
+// such as Redis Streams.
+type Delivery struct {
+	Topic     string
+	Partition int32
+	Offset    int64
+	MessageID string
+	Key       []byte
+	Value     []byte
+}
+
+// BatchHandler processes a micro-batch of deliveries. The batch is
+// acknowledged only when it returns nil; otherwise it is delivered again. It
+// may be called concurrently, for example once per Kafka partition.
+type BatchHandler func(ctx context.Context, deliveries []Delivery) error
+
+type SubscribeOptions struct {
+	Group     string
+	BatchSize int
+	BatchWait time.Duration
+	// FromOldest makes a new group start at the oldest retained message
+	// instead of the newest, where the transport supports it.
+	FromOldest bool
+	// Workers is how many goroutines handle each Kafka partition. Messages
+	// are split by key, so messages with the same key keep their order.
+	// Other transports ignore it.
+	Workers int
+}
+
+// Subscriber is the consuming side of the event transport. Subscribe blocks,
+// handing batches of the topic to handler, until ctx is cancelled or the
+// subscription fails.
+type Subscriber interface {
+	Subscribe(ctx context.Context, topic string, options SubscribeOptions, handler BatchHandler) error
+	Close() error
+}
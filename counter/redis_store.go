@@ -0,0 +1,177 @@
+package counter
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+	"tyrattribution/redis"
+
+	"github.com/google/uuid"
+)
+
+type RedisStore struct {
+	redisClient redis.Client
+}
+
+func NewRedisStore(redisClient redis.Client) Store {
+	return &RedisStore{redisClient: redisClient}
+}
+
+func (s *RedisStore) Increment(ctx context.Context, increments map[Key]int64) error {
+	if len(increments) == 0 {
+		return nil
+	}
+
+	return s.redisClient.Pipelined(ctx, func(pipe redis.Pipeline) error {
+		for key, increment := range increments {
+			pipe.IncrByExpireAt(ctx, key.String(), increment, key.ExpiresAt())
+		}
+		return nil
+	})
+}
+
+func (s *RedisStore) Get(ctx context.Context, key Key) (int64, error) {
+	values, err := s.redisClient.MGet(ctx, key.String())
+	if err != nil {
+		return 0, err
+	}
+
+	return parseCount(values, key)
+}
+
+func (s *RedisStore) GetDay(ctx context.Context, campaignID uuid.UUID, date time.Time) (Counts, error) {
+	impressionKey := NewKey(Impressions, campaignID, date)
+	clickKey := NewKey(Clicks, campaignID, date)
+	conversionKey := NewKey(Conversions, campaignID, date)
+	conversionValueKey := NewKey(ConversionValue, campaignID, date)
+
+	values, err := s.redisClient.MGet(ctx, impressionKey.String(), clickKey.String(), conversionKey.String(), conversionValueKey.String())
+	if err != nil {
+		return Counts{}, err
+	}
+
+	var counts Counts
+	if counts.Impressions, err = parseCount(values, impressionKey); err != nil {
+		return Counts{}, err
+	}
+	if counts.Clicks, err = parseCount(values, clickKey); err != nil {
+		return Counts{}, err
+	}
+	if counts.Conversions, err = parseCount(values, conversionKey); err != nil {
+		return Counts{}, err
+	}
+	if counts.ConversionValueCents, err = parseCount(values, conversionValueKey); err != nil {
+		return Counts{}, err
+	}
+	if counts.UniqueClickUsers, err = s.redisClient.PFCount(ctx, NewKey(ClickUsers, campaignID, date).String()); err != nil {
+		return Counts{}, err
+	}
+	if counts.UniqueConversionUsers, err = s.redisClient.PFCount(ctx, NewKey(ConversionUsers, campaignID, date).String()); err != nil {
+		return Counts{}, err
+	}
+
+	return counts, nil
+}
+
+func (s *RedisStore) SetDay(ctx context.Context, date time.Time, counts map[uuid.UUID]Counts) error {
+	expireAt := endOfNextDay(date)
+	if !expireAt.After(time.Now()) || len(counts) == 0 {
+		return nil
+	}
+
+	return s.redisClient.Pipelined(ctx, func(pipe redis.Pipeline) error {
+		for campaignID, count := range counts {
+			pipe.SetExpireAt(ctx, NewKey(Impressions, campaignID, date).String(), count.Impressions, expireAt)
+			pipe.SetExpireAt(ctx, NewKey(Clicks, campaignID, date).String(), count.Clicks, expireAt)
+			pipe.SetExpireAt(ctx, NewKey(Conversions, campaignID, date).String(), count.Conversions, expireAt)
+			pipe.SetExpireAt(ctx, NewKey(ConversionValue, campaignID, date).String(), count.ConversionValueCents, expireAt)
+		}
+		return nil
+	})
+}
+
+func (s *RedisStore) AddUsers(ctx context.Context, users map[Key][]uuid.UUID) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	return s.redisClient.Pipelined(ctx, func(pipe redis.Pipeline) error {
+		for key, userIDs := range users {
+			elements := make([]string, len(userIDs))
+			for i, userID := range userIDs {
+				elements[i] = userID.String()
+			}
+
+			pipe.PFAdd(ctx, key.String(), elements...)
+			pipe.ExpireAt(ctx, key.String(), key.ExpiresAt())
+		}
+		return nil
+	})
+}
+
+func (s *RedisStore) Sketch(ctx context.Context, key Key) ([]byte, error) {
+	values, err := s.redisClient.MGet(ctx, key.String())
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := values[key.String()]
+	if !ok {
+		return nil, nil
+	}
+
+	return []byte(value), nil
+}
+
+func (s *RedisStore) CountUnion(ctx context.Context, sketches [][]byte) (int64, error) {
+	// The sketches are loaded into short-lived keys sharing a hash tag, so
+	// PFCOUNT can merge them on a single Redis Cluster node too.
+	mergeID := uuid.NewString()
+	expireAt := time.Now().Add(time.Minute)
+
+	values := make(map[string][]byte, len(sketches))
+	keys := make([]string, 0, len(sketches))
+	for i, sketch := range sketches {
+		if len(sketch) == 0 {
+			continue
+		}
+
+		key := fmt.Sprintf("users_merge:{%s}:%d", mergeID, i)
+		values[key] = sketch
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	err := s.redisClient.Pipelined(ctx, func(pipe redis.Pipeline) error {
+		for key, sketch := range values {
+			pipe.SetExpireAt(ctx, key, sketch, expireAt)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return s.redisClient.PFCount(ctx, keys...)
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.redisClient.Ping(ctx)
+}
+
+func parseCount(values map[string]string, key Key) (int64, error) {
+	value, ok := values[key.String()]
+	if !ok {
+		return 0, nil
+	}
+
+	count, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse counter %s: %w", key.String(), err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,381 @@
+package counter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+	"tyrattribution/config"
+	"tyrattribution/entity"
+	"tyrattribution/redis"
+	"tyrattribution/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrUnavailable is returned by reads while the counters cannot be trusted,
+// that is while Redis is down or buffered updates have not been flushed yet.
+// Callers fall back to counting from Postgres.
+var ErrUnavailable = errors.New("counters unavailable")
+
+// Health describes the state of a ResilientStore.
+type Health struct {
+	Status          string     `json:"status"`
+	RedisAvailable  bool       `json:"redis_available"`
+	BufferedUpdates int64      `json:"buffered_updates"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastFailureAt   *time.Time `json:"last_failure_at,omitempty"`
+	LastFlushAt     *time.Time `json:"last_flush_at,omitempty"`
+}
+
+// ResilientStore keeps counting while Redis is unavailable. Updates that fail
+// are written to the counter_buffer table, and the flusher started by Start
+// adds them to Redis once it answers again. Updates are flushed at least once,
+// so a crash between the Redis write and the delete of the buffered rows
+// counts them twice. An error reply from Redis means the rest of the batch
+// was applied, so such updates are reported rather than buffered.
+//
+// Reads are refused while the table holds updates. Each flusher pass checks
+// the table, so instances learn of updates buffered by another instance
+// within one interval.
+type ResilientStore struct {
+	store                   Store
+	counterBufferRepository repository.CounterBufferRepository
+	batchSize               int
+	interval                time.Duration
+
+	available atomic.Bool
+	// pending is set while the buffer may hold updates. It is only changed
+	// under mu, where bufferWrites lets the flusher clear it without missing a
+	// write made during its last pass.
+	pending atomic.Bool
+
+	mu            sync.Mutex
+	bufferWrites  int64
+	lastError     string
+	lastFailureAt *time.Time
+	lastFlushAt   *time.Time
+}
+
+func NewResilientStore(cfg *config.Config, store Store, counterBufferRepository repository.CounterBufferRepository) *ResilientStore {
+	s := &ResilientStore{
+		store:                   store,
+		counterBufferRepository: counterBufferRepository,
+		batchSize:               cfg.CounterFlushBatchSize,
+		interval:                time.Duration(cfg.CounterFlushIntervalSeconds) * time.Second,
+	}
+
+	s.available.Store(true)
+	// Updates buffered before a restart are unknown until the first flush.
+	s.pending.Store(true)
+
+	return s
+}
+
+func (s *ResilientStore) Increment(ctx context.Context, increments map[Key]int64) error {
+	if len(increments) == 0 {
+		return nil
+	}
+
+	if s.available.Load() {
+		err := s.store.Increment(ctx, increments)
+		if err == nil || redis.IsReplyError(err) {
+			return err
+		}
+		s.markUnavailable(err)
+	}
+
+	counterBuffers := make([]entity.CounterBuffer, 0, len(increments))
+	for key, increment := range increments {
+		counterBuffers = append(counterBuffers, entity.CounterBuffer{
+			Kind:       string(key.Kind),
+			CampaignID: key.CampaignID,
+			Date:       key.Date,
+			Increment:  increment,
+		})
+	}
+
+	return s.buffer(ctx, counterBuffers)
+}
+
+func (s *ResilientStore) AddUsers(ctx context.Context, users map[Key][]uuid.UUID) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	if s.available.Load() {
+		err := s.store.AddUsers(ctx, users)
+		if err == nil || redis.IsReplyError(err) {
+			return err
+		}
+		s.markUnavailable(err)
+	}
+
+	var counterBuffers []entity.CounterBuffer
+	for key, userIDs := range users {
+		for _, userID := range userIDs {
+			counterBuffers = append(counterBuffers, entity.CounterBuffer{
+				Kind:       string(key.Kind),
+				CampaignID: key.CampaignID,
+				Date:       key.Date,
+				UserID:     &userID,
+			})
+		}
+	}
+
+	return s.buffer(ctx, counterBuffers)
+}
+
+func (s *ResilientStore) Get(ctx context.Context, key Key) (int64, error) {
+	if err := s.checkReadable(); err != nil {
+		return 0, err
+	}
+
+	count, err := s.store.Get(ctx, key)
+	if err != nil {
+		s.markUnavailable(err)
+		return 0, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	return count, nil
+}
+
+func (s *ResilientStore) GetDay(ctx context.Context, campaignID uuid.UUID, date time.Time) (Counts, error) {
+	if err := s.checkReadable(); err != nil {
+		return Counts{}, err
+	}
+
+	counts, err := s.store.GetDay(ctx, campaignID, date)
+	if err != nil {
+		s.markUnavailable(err)
+		return Counts{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	return counts, nil
+}
+
+// SetDay is refused while updates are buffered, since flushing them after the
+// overwrite would count them twice.
+func (s *ResilientStore) SetDay(ctx context.Context, date time.Time, counts map[uuid.UUID]Counts) error {
+	if err := s.checkReadable(); err != nil {
+		return err
+	}
+
+	if err := s.store.SetDay(ctx, date, counts); err != nil {
+		s.markUnavailable(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *ResilientStore) Sketch(ctx context.Context, key Key) ([]byte, error) {
+	if err := s.checkReadable(); err != nil {
+		return nil, err
+	}
+
+	sketch, err := s.store.Sketch(ctx, key)
+	if err != nil {
+		s.markUnavailable(err)
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	return sketch, nil
+}
+
+// CountUnion only merges sketches already read, so buffered updates do not
+// matter to it.
+func (s *ResilientStore) CountUnion(ctx context.Context, sketches [][]byte) (int64, error) {
+	if !s.available.Load() {
+		return 0, ErrUnavailable
+	}
+
+	count, err := s.store.CountUnion(ctx, sketches)
+	if err != nil {
+		s.markUnavailable(err)
+		return 0, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	return count, nil
+}
+
+func (s *ResilientStore) Ping(ctx context.Context) error {
+	return s.store.Ping(ctx)
+}
+
+// Health reports whether Redis is reachable and how many updates wait in the
+// buffer.
+func (s *ResilientStore) Health(ctx context.Context) (*Health, error) {
+	buffered, err := s.counterBufferRepository.Count(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count buffered counter updates: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := &Health{
+		Status:          "ok",
+		RedisAvailable:  s.available.Load(),
+		BufferedUpdates: buffered,
+		LastError:       s.lastError,
+		LastFailureAt:   s.lastFailureAt,
+		LastFlushAt:     s.lastFlushAt,
+	}
+	if !health.RedisAvailable || buffered > 0 {
+		health.Status = "degraded"
+	}
+
+	return health, nil
+}
+
+// Start checks Redis and flushes buffered updates until the context is
+// cancelled. A batch that flushed anything is followed straight away by the
+// next one.
+func (s *ResilientStore) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		flushed, err := s.flush(ctx)
+		if err != nil {
+			log.Printf("Failed to flush buffered counter updates: %v", err)
+		}
+
+		if flushed > 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Counter flusher context cancelled")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ResilientStore) flush(ctx context.Context) (int, error) {
+	if !s.available.Load() {
+		if err := s.store.Ping(ctx); err != nil {
+			return 0, nil
+		}
+		s.available.Store(true)
+		log.Println("Redis is reachable again, flushing buffered counter updates")
+	}
+
+	s.mu.Lock()
+	writes := s.bufferWrites
+	s.mu.Unlock()
+
+	flushed, err := s.counterBufferRepository.FlushPending(ctx, s.batchSize, func(counterBuffers []entity.CounterBuffer) error {
+		increments := make(map[Key]int64)
+		users := make(map[Key][]uuid.UUID)
+
+		for _, counterBuffer := range counterBuffers {
+			key := Key{
+				Kind:       Kind(counterBuffer.Kind),
+				CampaignID: counterBuffer.CampaignID,
+				Date:       counterBuffer.Date,
+			}
+
+			if counterBuffer.UserID != nil {
+				users[key] = append(users[key], *counterBuffer.UserID)
+			} else {
+				increments[key] += counterBuffer.Increment
+			}
+		}
+
+		// Users go first, since adding them again after a failed increment
+		// does not change the sketches. A reply error cannot be fixed by
+		// retrying, and the rest of the batch was applied, so the rows are
+		// dropped.
+		if err := s.store.AddUsers(ctx, users); err != nil && !redis.IsReplyError(err) {
+			s.markUnavailable(err)
+			return err
+		} else if err != nil {
+			log.Printf("Dropping buffered unique users Redis rejected: %v", err)
+		}
+		if err := s.store.Increment(ctx, increments); err != nil && !redis.IsReplyError(err) {
+			s.markUnavailable(err)
+			return err
+		} else if err != nil {
+			log.Printf("Dropping buffered counter increments Redis rejected: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if flushed > 0 {
+		now := time.Now()
+		s.mu.Lock()
+		s.lastFlushAt = &now
+		s.mu.Unlock()
+
+		log.Printf("Flushed %d buffered counter updates to Redis", flushed)
+		return flushed, nil
+	}
+
+	// Nothing was flushed, but rows locked by another instance may remain.
+	buffered, err := s.counterBufferRepository.Exists(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check buffered counter updates: %w", err)
+	}
+
+	s.mu.Lock()
+	if buffered {
+		s.pending.Store(true)
+	} else if s.bufferWrites == writes {
+		s.pending.Store(false)
+	}
+	s.mu.Unlock()
+
+	return 0, nil
+}
+
+func (s *ResilientStore) buffer(ctx context.Context, counterBuffers []entity.CounterBuffer) error {
+	if err := s.counterBufferRepository.CreateBatch(ctx, counterBuffers); err != nil {
+		return fmt.Errorf("failed to buffer %d counter updates: %w", len(counterBuffers), err)
+	}
+
+	s.mu.Lock()
+	s.bufferWrites++
+	s.pending.Store(true)
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *ResilientStore) checkReadable() error {
+	if !s.available.Load() || s.pending.Load() {
+		return ErrUnavailable
+	}
+	return nil
+}
+
+func (s *ResilientStore) markUnavailable(err error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.lastFailureAt = &now
+	s.mu.Unlock()
+
+	if s.available.Swap(false) {
+		log.Printf("Redis unavailable, buffering counter updates in Postgres: %v", err)
+	}
+}
+
+func StartCounterFlusher(ctx context.Context, store *ResilientStore) {
+	log.Println("Starting counter flusher")
+	store.Start(ctx)
+}
@@ -0,0 +1,123 @@
+package counter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DateLayout is the format of the date in counter keys.
+const DateLayout = "2006-01-02"
+
+// Kind names one of the daily per-campaign counters.
+type Kind string
+
+const (
+	Impressions Kind = "impression_count"
+	Clicks      Kind = "click_count"
+	Conversions Kind = "conversion_count"
+	// ConversionValue holds the attributed conversion value in cents, so the
+	// sum stays exact.
+	ConversionValue Kind = "conversion_value_cents"
+	// ClickUsers and ConversionUsers are HyperLogLog sketches of the users
+	// who clicked and who converted.
+	ClickUsers      Kind = "click_users"
+	ConversionUsers Kind = "conversion_users"
+)
+
+// Key identifies the counter of a kind for a campaign on a day.
+type Key struct {
+	Kind       Kind
+	CampaignID uuid.UUID
+	Date       string
+}
+
+func NewKey(kind Kind, campaignID uuid.UUID, date time.Time) Key {
+	return Key{
+		Kind:       kind,
+		CampaignID: campaignID,
+		Date:       date.Format(DateLayout),
+	}
+}
+
+// String returns the Redis key, e.g. click_count:<campaign_id>:2006-01-02.
+func (k Key) String() string {
+	return fmt.Sprintf("%s:%s:%s", k.Kind, k.CampaignID.String(), k.Date)
+}
+
+// ExpiresAt returns the end of the day after the counter's date. Counters only
+// need to outlive the nightly journal job that reads yesterday's values.
+func (k Key) ExpiresAt() time.Time {
+	date, err := time.ParseInLocation(DateLayout, k.Date, time.Local)
+	if err != nil {
+		return time.Now()
+	}
+
+	return endOfNextDay(date)
+}
+
+// Counts holds a campaign's counters for one day.
+type Counts struct {
+	Impressions int64
+	Clicks      int64
+	Conversions int64
+	// ConversionValueCents is the attributed conversion value in cents.
+	ConversionValueCents int64
+	// UniqueClickUsers and UniqueConversionUsers are HyperLogLog estimates.
+	UniqueClickUsers      int64
+	UniqueConversionUsers int64
+}
+
+// Cents converts a conversion value to whole cents. Values are stored with two
+// decimal places, so no rounding happens in practice.
+func Cents(value decimal.Decimal) int64 {
+	return value.Shift(2).Round(0).IntPart()
+}
+
+// FromCents converts cents back to a conversion value.
+func FromCents(cents int64) decimal.Decimal {
+	return decimal.New(cents, -2)
+}
+
+// Store keeps the daily per-campaign event counters. Every counter expires at
+// the end of the day after its date, whenever it is written.
+type Store interface {
+	// Increment adds each increment to its counter, setting the counter's
+	// expiry in the same step.
+	Increment(ctx context.Context, increments map[Key]int64) error
+	// Get returns the counter's value, or zero when it does not exist.
+	Get(ctx context.Context, key Key) (int64, error)
+	// GetDay returns the campaign's counters for the date.
+	GetDay(ctx context.Context, campaignID uuid.UUID, date time.Time) (Counts, error)
+	// SetDay overwrites the counters of each campaign for the date. The
+	// unique user sketches are left as they are.
+	SetDay(ctx context.Context, date time.Time, counts map[uuid.UUID]Counts) error
+	// AddUsers adds the users to the sketch at each key, setting the sketch's
+	// expiry in the same step.
+	AddUsers(ctx context.Context, users map[Key][]uuid.UUID) error
+	// Sketch returns the serialized sketch at the key, or nil when it does not
+	// exist.
+	Sketch(ctx context.Context, key Key) ([]byte, error)
+	// CountUnion merges serialized sketches and returns the estimated number of
+	// distinct users across them.
+	CountUnion(ctx context.Context, sketches [][]byte) (int64, error)
+	// Ping reports whether the backing store is reachable.
+	Ping(ctx context.Context) error
+}
+
+// IsLive reports whether the counters for the date still exist, which is only
+// the case for today's and yesterday's.
+func IsLive(date time.Time, now time.Time) bool {
+	yesterday := now.AddDate(0, 0, -1)
+	startOfYesterday := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, yesterday.Location())
+
+	return !date.Before(startOfYesterday)
+}
+
+func endOfNextDay(date time.Time) time.Time {
+	nextDay := date.AddDate(0, 0, 1)
+	return time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 23, 59, 59, 0, nextDay.Location())
+}
@@ -0,0 +1,14 @@
+CREATE TABLE attribution_credit (
+    attribution_credit_id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    conversion_id UUID NOT NULL,
+    click_id UUID,
+    impression_id UUID,
+    campaign_id UUID NOT NULL,
+    model VARCHAR(50) NOT NULL,
+    weight DECIMAL(7,6) NOT NULL,
+    credited_value DECIMAL(10,2),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_attribution_credit_conversion ON attribution_credit (conversion_id);
+CREATE INDEX idx_attribution_credit_campaign_model ON attribution_credit (campaign_id, model);
@@ -2,8 +2,13 @@ CREATE TABLE campaign_journal (
     campaign_journal_id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
     campaign_id UUID NOT NULL,
     date DATE NOT NULL,
+    number_of_impression BIGINT,
     number_of_click BIGINT,
     number_of_conversion BIGINT,
     total_conversion_value DECIMAL(10,2),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    unique_click_users BIGINT,
+    unique_conversion_users BIGINT,
+    click_users_sketch BYTEA,
+    conversion_users_sketch BYTEA
 );
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE campaign_setting (
+    campaign_id UUID PRIMARY KEY,
+    lookback_window_hours INTEGER,
+    attribution_model VARCHAR(50),
+    time_decay_half_life_hours INTEGER,
+    counted_conversion_types TEXT,
+    view_through_window_hours INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
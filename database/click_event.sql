@@ -7,4 +7,5 @@ CREATE TABLE click_event (
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
-CREATE INDEX idx_click_event_composite ON click_event (campaign_id, user_id, source, click_date);
\ No newline at end of file
+CREATE INDEX idx_click_event_composite ON click_event (campaign_id, user_id, source, click_date);
+CREATE INDEX idx_click_event_user_date ON click_event (user_id, click_date);
\ No newline at end of file
@@ -3,11 +3,15 @@ CREATE TABLE conversion_event (
     user_id UUID NOT NULL,
     campaign_id UUID NOT NULL,
     click_id UUID,
+    impression_id UUID,
     conversion_date TIMESTAMP NOT NULL,
     value DECIMAL(10,2),
     type VARCHAR(255) NOT NULL,
     source VARCHAR(255) NOT NULL,
+    order_id VARCHAR(255),
+    is_duplicate BOOLEAN DEFAULT FALSE NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
 );
 
-CREATE INDEX idx_conversion_event_campaign ON conversion_event (campaign_id);
\ No newline at end of file
+CREATE INDEX idx_conversion_event_campaign ON conversion_event (campaign_id);
+CREATE INDEX idx_conversion_event_campaign_order ON conversion_event (campaign_id, order_id);
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE counter_buffer (
+    counter_buffer_id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(64) NOT NULL,
+    campaign_id UUID NOT NULL,
+    date VARCHAR(10) NOT NULL,
+    increment BIGINT DEFAULT 0 NOT NULL,
+    user_id UUID,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
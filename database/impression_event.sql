@@ -0,0 +1,11 @@
+CREATE TABLE impression_event (
+    impression_id UUID DEFAULT gen_random_uuid() PRIMARY KEY,
+    campaign_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    impression_date TIMESTAMP NOT NULL,
+    source VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_impression_event_composite ON impression_event (campaign_id, user_id, source, impression_date);
+CREATE INDEX idx_impression_event_user_date ON impression_event (user_id, impression_date);
@@ -0,0 +1,15 @@
+CREATE TABLE outbox_event (
+    outbox_event_id BIGSERIAL PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    message_key BYTEA,
+    payload BYTEA NOT NULL,
+    attempts INTEGER DEFAULT 0 NOT NULL,
+    last_error TEXT,
+    retry_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    failed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX idx_outbox_event_pending ON outbox_event (outbox_event_id) WHERE sent_at IS NULL AND failed_at IS NULL;
+CREATE INDEX idx_outbox_event_sent ON outbox_event (sent_at);
\ No newline at end of file
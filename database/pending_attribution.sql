@@ -0,0 +1,13 @@
+CREATE TABLE pending_attribution (
+    conversion_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    click_id UUID,
+    attempts INTEGER DEFAULT 0 NOT NULL,
+    last_error TEXT,
+    retry_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_pending_attribution_retry ON pending_attribution (retry_at);
+CREATE INDEX idx_pending_attribution_user ON pending_attribution (user_id);
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE tracked_link (
+    link_id VARCHAR(64) PRIMARY KEY,
+    campaign_id UUID NOT NULL,
+    source VARCHAR(255) NOT NULL,
+    destination_url TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_tracked_link_campaign ON tracked_link (campaign_id);
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type AttributionCredit struct {
+	AttributionCreditID uuid.UUID        `json:"attribution_credit_id" gorm:"type:uuid;default:gen_random_uuid();primaryKey;column:attribution_credit_id"`
+	ConversionID        uuid.UUID        `json:"conversion_id" gorm:"type:uuid;not null;column:conversion_id;index"`
+	ClickID             *uuid.UUID       `json:"click_id" gorm:"type:uuid;column:click_id"`
+	ImpressionID        *uuid.UUID       `json:"impression_id" gorm:"type:uuid;column:impression_id"`
+	CampaignID          uuid.UUID        `json:"campaign_id" gorm:"type:uuid;not null;column:campaign_id;index"`
+	Model               string           `json:"model" gorm:"type:varchar(50);not null;column:model"`
+	Weight              decimal.Decimal  `json:"weight" gorm:"type:decimal(7,6);not null;column:weight"`
+	CreditedValue       *decimal.Decimal `json:"credited_value" gorm:"type:decimal(10,2);column:credited_value"`
+	CreatedAt           time.Time        `json:"created_at" gorm:"autoCreateTime;column:created_at"`
+}
+
+func (AttributionCredit) TableName() string {
+	return "attribution_credit"
+}
@@ -11,10 +11,17 @@ type CampaignJournal struct {
 	CampaignJournalID    uuid.UUID        `json:"campaign_journal_id" gorm:"type:uuid;default:gen_random_uuid();primaryKey;column:campaign_journal_id"`
 	CampaignID           uuid.UUID        `json:"campaign_id" gorm:"type:uuid;not null;column:campaign_id"`
 	Date                 time.Time        `json:"date" gorm:"type:date;not null;column:date"`
+	NumberOfImpression   *int64           `json:"number_of_impression" gorm:"type:bigint;column:number_of_impression"`
 	NumberOfClick        *int64           `json:"number_of_click" gorm:"type:bigint;column:number_of_click"`
 	NumberOfConversion   *int64           `json:"number_of_conversion" gorm:"type:bigint;column:number_of_conversion"`
 	TotalConversionValue *decimal.Decimal `json:"total_conversion_value" gorm:"type:decimal(10,2);column:total_conversion_value"`
 	CreatedAt            time.Time        `json:"created_at" gorm:"autoCreateTime;column:created_at"`
+	// Unique users are HyperLogLog estimates. The sketches are kept so that
+	// weekly and monthly reach can be merged from the daily rows.
+	UniqueClickUsers      *int64 `json:"unique_click_users" gorm:"type:bigint;column:unique_click_users"`
+	UniqueConversionUsers *int64 `json:"unique_conversion_users" gorm:"type:bigint;column:unique_conversion_users"`
+	ClickUsersSketch      []byte `json:"-" gorm:"type:bytea;column:click_users_sketch"`
+	ConversionUsersSketch []byte `json:"-" gorm:"type:bytea;column:conversion_users_sketch"`
 }
 
 func (CampaignJournal) TableName() string {
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CampaignSetting overrides the global attribution configuration for a single
+// campaign. Nil fields fall back to the global defaults.
+type CampaignSetting struct {
+	CampaignID             uuid.UUID `json:"campaign_id" gorm:"type:uuid;primaryKey;column:campaign_id"`
+	LookbackWindowHours    *int      `json:"lookback_window_hours" gorm:"type:integer;column:lookback_window_hours"`
+	AttributionModel       *string   `json:"attribution_model" gorm:"type:varchar(50);column:attribution_model"`
+	TimeDecayHalfLifeHours *int      `json:"time_decay_half_life_hours" gorm:"type:integer;column:time_decay_half_life_hours"`
+	CountedConversionTypes *string   `json:"counted_conversion_types" gorm:"type:text;column:counted_conversion_types"`
+	ViewThroughWindowHours *int      `json:"view_through_window_hours" gorm:"type:integer;column:view_through_window_hours"`
+	CreatedAt              time.Time `json:"created_at" gorm:"autoCreateTime;column:created_at"`
+	UpdatedAt              time.Time `json:"updated_at" gorm:"autoUpdateTime;column:updated_at"`
+}
+
+func (CampaignSetting) TableName() string {
+	return "campaign_setting"
+}
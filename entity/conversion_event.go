@@ -10,12 +10,15 @@ import (
 type ConversionEvent struct {
 	ConversionID   uuid.UUID        `json:"conversion_id" gorm:"type:uuid;default:gen_random_uuid();primaryKey;column:conversion_id"`
 	UserID         uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;column:user_id"`
-	CampaignID     uuid.UUID        `json:"campaign_id" gorm:"type:uuid;not null;column:campaign_id;index"`
+	CampaignID     uuid.UUID        `json:"campaign_id" gorm:"type:uuid;not null;column:campaign_id;index;index:idx_conversion_event_campaign_order"`
 	ClickID        *uuid.UUID       `json:"click_id" gorm:"type:uuid;column:click_id"`
+	ImpressionID   *uuid.UUID       `json:"impression_id" gorm:"type:uuid;column:impression_id"`
 	ConversionDate time.Time        `json:"conversion_date" gorm:"not null;column:conversion_date"`
 	Value          *decimal.Decimal `json:"value" gorm:"type:decimal(10,2);column:value"`
 	Type           string           `json:"type" gorm:"type:varchar(255);not null;column:type"`
 	Source         string           `json:"source" gorm:"type:varchar(255);not null;column:source"`
+	OrderID        *string          `json:"order_id" gorm:"type:varchar(255);column:order_id;index:idx_conversion_event_campaign_order"`
+	IsDuplicate    bool             `json:"is_duplicate" gorm:"not null;default:false;column:is_duplicate"`
 	CreatedAt      time.Time        `json:"created_at" gorm:"autoCreateTime;column:created_at"`
 }
 
@@ -0,0 +1,24 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CounterBuffer is a counter update held in Postgres while Redis is
+// unavailable. Increment rows add to a counter; rows with a user ID add the
+// user to a unique user sketch.
+type CounterBuffer struct {
+	CounterBufferID int64      `json:"counter_buffer_id" gorm:"primaryKey;autoIncrement;column:counter_buffer_id"`
+	Kind            string     `json:"kind" gorm:"type:varchar(64);not null;column:kind"`
+	CampaignID      uuid.UUID  `json:"campaign_id" gorm:"type:uuid;not null;column:campaign_id"`
+	Date            string     `json:"date" gorm:"type:varchar(10);not null;column:date"`
+	Increment       int64      `json:"increment" gorm:"type:bigint;not null;default:0;column:increment"`
+	UserID          *uuid.UUID `json:"user_id" gorm:"type:uuid;column:user_id"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime;column:created_at"`
+}
+
+func (CounterBuffer) TableName() string {
+	return "counter_buffer"
+}
@@ -0,0 +1,20 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ImpressionEvent struct {
+	ImpressionID   uuid.UUID `json:"impression_id" gorm:"type:uuid;default:gen_random_uuid();primaryKey;column:impression_id"`
+	CampaignID     uuid.UUID `json:"campaign_id" gorm:"type:uuid;not null;column:campaign_id;index:idx_impression_event_composite"`
+	UserID         uuid.UUID `json:"user_id" gorm:"type:uuid;not null;column:user_id;index:idx_impression_event_composite;index:idx_impression_event_user_date"`
+	ImpressionDate time.Time `json:"impression_date" gorm:"not null;column:impression_date;index:idx_impression_event_composite;index:idx_impression_event_user_date"`
+	Source         string    `json:"source" gorm:"type:varchar(255);not null;column:source;index:idx_impression_event_composite"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime;column:created_at"`
+}
+
+func (ImpressionEvent) TableName() string {
+	return "impression_event"
+}
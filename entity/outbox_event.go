@@ -0,0 +1,22 @@
+package entity
+
+import (
+	"time"
+)
+
+type OutboxEvent struct {
+	OutboxEventID int64      `json:"outbox_event_id" gorm:"primaryKey;autoIncrement;column:outbox_event_id"`
+	Topic         string     `json:"topic" gorm:"type:varchar(255);not null;column:topic"`
+	MessageKey    []byte     `json:"message_key" gorm:"type:bytea;column:message_key"`
+	Payload       []byte     `json:"payload" gorm:"type:bytea;not null;column:payload"`
+	Attempts      int        `json:"attempts" gorm:"not null;default:0;column:attempts"`
+	LastError     *string    `json:"last_error" gorm:"type:text;column:last_error"`
+	RetryAt       time.Time  `json:"retry_at" gorm:"not null;column:retry_at"`
+	FailedAt      *time.Time `json:"failed_at" gorm:"column:failed_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime;column:created_at"`
+	SentAt        *time.Time `json:"sent_at" gorm:"column:sent_at;index:idx_outbox_event_sent"`
+}
+
+func (OutboxEvent) TableName() string {
+	return "outbox_event"
+}
@@ -0,0 +1,25 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PendingAttribution queues a conversion that matched no click or impression
+// when it was processed, so attribution can be retried as late clicks arrive
+// until its look-back window has closed at ExpiresAt.
+type PendingAttribution struct {
+	ConversionID uuid.UUID  `json:"conversion_id" gorm:"type:uuid;primaryKey;column:conversion_id"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;column:user_id;index:idx_pending_attribution_user"`
+	ClickID      *uuid.UUID `json:"click_id" gorm:"type:uuid;column:click_id"`
+	Attempts     int        `json:"attempts" gorm:"not null;default:0;column:attempts"`
+	LastError    *string    `json:"last_error" gorm:"type:text;column:last_error"`
+	RetryAt      time.Time  `json:"retry_at" gorm:"not null;column:retry_at;index:idx_pending_attribution_retry"`
+	ExpiresAt    time.Time  `json:"expires_at" gorm:"not null;column:expires_at"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime;column:created_at"`
+}
+
+func (PendingAttribution) TableName() string {
+	return "pending_attribution"
+}
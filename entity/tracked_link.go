@@ -0,0 +1,19 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TrackedLink struct {
+	LinkID         string    `json:"link_id" gorm:"type:varchar(64);primaryKey;column:link_id"`
+	CampaignID     uuid.UUID `json:"campaign_id" gorm:"type:uuid;not null;column:campaign_id;index"`
+	Source         string    `json:"source" gorm:"type:varchar(255);not null;column:source"`
+	DestinationURL string    `json:"destination_url" gorm:"type:text;not null;column:destination_url"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime;column:created_at"`
+}
+
+func (TrackedLink) TableName() string {
+	return "tracked_link"
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"tyrattribution/attribution"
+	"tyrattribution/entity"
+	"tyrattribution/service"
+
+	"github.com/google/uuid"
+)
+
+type CampaignSettingHandler struct {
+	campaignSettingService service.CampaignSettingService
+}
+
+func NewCampaignSettingHandler(campaignSettingService service.CampaignSettingService) *CampaignSettingHandler {
+	return &CampaignSettingHandler{
+		campaignSettingService: campaignSettingService,
+	}
+}
+
+type CampaignSettingRequest struct {
+	CampaignID             string   `json:"campaign_id"`
+	LookbackWindowHours    *int     `json:"lookback_window_hours,omitempty"`
+	AttributionModel       *string  `json:"attribution_model,omitempty"`
+	TimeDecayHalfLifeHours *int     `json:"time_decay_half_life_hours,omitempty"`
+	CountedConversionTypes []string `json:"counted_conversion_types,omitempty"`
+	ViewThroughWindowHours *int     `json:"view_through_window_hours,omitempty"`
+}
+
+type CampaignSettingResponse struct {
+	CampaignID             string   `json:"campaign_id"`
+	LookbackWindowHours    int      `json:"lookback_window_hours"`
+	AttributionModel       string   `json:"attribution_model"`
+	CountedConversionTypes []string `json:"counted_conversion_types"`
+	ViewThroughWindowHours int      `json:"view_through_window_hours"`
+}
+
+type CampaignSettingUpdateResponse struct {
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (h *CampaignSettingHandler) GetCampaignSetting(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	campaignIDStr := r.URL.Query().Get("campaign_id")
+	if campaignIDStr == "" {
+		http.Error(w, "campaign_id parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	campaignID, err := uuid.Parse(campaignIDStr)
+	if err != nil {
+		http.Error(w, "Invalid campaign_id format", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.campaignSettingService.GetAttributionSettings(r.Context(), campaignID)
+	if err != nil {
+		http.Error(w, "Failed to get campaign settings", http.StatusInternalServerError)
+		return
+	}
+
+	response := CampaignSettingResponse{
+		CampaignID:             campaignID.String(),
+		LookbackWindowHours:    settings.LookbackWindowHours,
+		AttributionModel:       settings.Model.Name(),
+		CountedConversionTypes: settings.CountedConversionTypes,
+		ViewThroughWindowHours: settings.ViewThroughWindowHours,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *CampaignSettingHandler) UpdateCampaignSetting(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req CampaignSettingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	campaignID, err := uuid.Parse(req.CampaignID)
+	if err != nil {
+		http.Error(w, "Invalid campaign_id format", http.StatusBadRequest)
+		return
+	}
+
+	if req.LookbackWindowHours != nil && *req.LookbackWindowHours <= 0 {
+		http.Error(w, "lookback_window_hours must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if req.TimeDecayHalfLifeHours != nil && *req.TimeDecayHalfLifeHours <= 0 {
+		http.Error(w, "time_decay_half_life_hours must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if req.ViewThroughWindowHours != nil && *req.ViewThroughWindowHours < 0 {
+		http.Error(w, "view_through_window_hours must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if req.AttributionModel != nil {
+		if err := attribution.ValidateModelName(*req.AttributionModel); err != nil {
+			http.Error(w, "Invalid attribution_model", http.StatusBadRequest)
+			return
+		}
+	}
+
+	campaignSetting := &entity.CampaignSetting{
+		CampaignID:             campaignID,
+		LookbackWindowHours:    req.LookbackWindowHours,
+		AttributionModel:       req.AttributionModel,
+		TimeDecayHalfLifeHours: req.TimeDecayHalfLifeHours,
+		ViewThroughWindowHours: req.ViewThroughWindowHours,
+	}
+
+	if len(req.CountedConversionTypes) > 0 {
+		countedConversionTypes := strings.Join(req.CountedConversionTypes, ",")
+		campaignSetting.CountedConversionTypes = &countedConversionTypes
+	}
+
+	if err := h.campaignSettingService.UpdateCampaignSetting(r.Context(), campaignSetting); err != nil {
+		http.Error(w, "Failed to update campaign settings", http.StatusInternalServerError)
+		return
+	}
+
+	response := CampaignSettingUpdateResponse{
+		Message: "Campaign settings updated successfully",
+		Status:  "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
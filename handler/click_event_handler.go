@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 	"tyrattribution/publisher"
@@ -10,10 +11,10 @@ import (
 )
 
 type ClickEventHandler struct {
-	clickEventPub *publisher.ClickEventPublisher
+	clickEventPub publisher.ClickEventPublisher
 }
 
-func NewClickEventHandler(clickEventPub *publisher.ClickEventPublisher) *ClickEventHandler {
+func NewClickEventHandler(clickEventPub publisher.ClickEventPublisher) *ClickEventHandler {
 	return &ClickEventHandler{
 		clickEventPub: clickEventPub,
 	}
@@ -45,62 +46,70 @@ func (h *ClickEventHandler) CreateClickEvent(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	campaignID, err := uuid.Parse(req.CampaignID)
+	if req.ClickID == "" {
+		req.ClickID = idempotentEventID(r, batchEventTypeClick)
+	}
+
+	clickEvent, err := buildClickEvent(req)
 	if err != nil {
-		http.Error(w, "Invalid campaign_id format", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	if err := h.clickEventPub.PublishClickEvent(clickEvent); err != nil {
+		http.Error(w, "Failed to create click event", http.StatusInternalServerError)
+		return
+	}
+
+	response := ClickEventResponse{
+		ClickID: clickEvent.ClickID.String(),
+		Message: "Click event created successfully",
+		Status:  "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// buildClickEvent validates a click request and maps it to the event that is
+// published to Kafka. The returned error message is safe to show to clients.
+func buildClickEvent(req ClickEventRequest) (publisher.ClickEvent, error) {
+	campaignID, err := uuid.Parse(req.CampaignID)
+	if err != nil {
+		return publisher.ClickEvent{}, errors.New("Invalid campaign_id format")
+	}
+
 	userID, err := uuid.Parse(req.UserID)
 	if err != nil {
-		http.Error(w, "Invalid user_id format", http.StatusBadRequest)
-		return
+		return publisher.ClickEvent{}, errors.New("Invalid user_id format")
 	}
 
 	clickDate, err := time.Parse(time.RFC3339, req.ClickDate)
 	if err != nil {
-		http.Error(w, "Invalid click_date format, use RFC3339", http.StatusBadRequest)
-		return
+		return publisher.ClickEvent{}, errors.New("Invalid click_date format, use RFC3339")
 	}
 
 	if req.Source == "" {
-		http.Error(w, "Source is required", http.StatusBadRequest)
-		return
+		return publisher.ClickEvent{}, errors.New("Source is required")
 	}
 
 	var clickID uuid.UUID
 	if req.ClickID != "" {
-		var err error
 		clickID, err = uuid.Parse(req.ClickID)
 		if err != nil {
-			http.Error(w, "Invalid click_id format", http.StatusBadRequest)
-			return
+			return publisher.ClickEvent{}, errors.New("Invalid click_id format")
 		}
 	} else {
 		clickID = uuid.New()
 	}
 
-	clickEvent := publisher.ClickEvent{
+	return publisher.ClickEvent{
 		ClickID:    clickID,
 		CampaignID: campaignID,
 		UserID:     userID,
 		ClickDate:  clickDate,
 		Source:     req.Source,
 		CreatedAt:  time.Now(),
-	}
-
-	if err := h.clickEventPub.PublishClickEvent(clickEvent); err != nil {
-		http.Error(w, "Failed to create click event", http.StatusInternalServerError)
-		return
-	}
-
-	response := ClickEventResponse{
-		ClickID: clickEvent.ClickID.String(),
-		Message: "Click event created successfully",
-		Status:  "success",
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
+	}, nil
 }
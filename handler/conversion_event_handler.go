@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -10,24 +11,33 @@ import (
 	"tyrattribution/publisher"
 )
 
+// maxConversionValue bounds the value of a conversion, which is stored as
+// decimal(10,2).
+var maxConversionValue = decimal.New(1, 8)
+
 type ConversionEventHandler struct {
-	conversionEventPub *publisher.ConversionEventPublisher
+	conversionEventPub publisher.ConversionEventPublisher
+	crossCampaign      bool
 }
 
-func NewConversionEventHandler(conversionEventPub *publisher.ConversionEventPublisher) *ConversionEventHandler {
+func NewConversionEventHandler(conversionEventPub publisher.ConversionEventPublisher, crossCampaign bool) *ConversionEventHandler {
 	return &ConversionEventHandler{
 		conversionEventPub: conversionEventPub,
+		crossCampaign:      crossCampaign,
 	}
 }
 
 type ConversionEventRequest struct {
-	ConversionID   string  `json:"conversion_id,omitempty"`
-	UserID         string  `json:"user_id"`
-	CampaignID     string  `json:"campaign_id"`
-	ConversionDate string  `json:"conversion_date"`
-	Value          float64 `json:"value"`
-	Type           string  `json:"type"`
-	Source         string  `json:"source"`
+	ConversionID   string           `json:"conversion_id,omitempty"`
+	UserID         string           `json:"user_id"`
+	CampaignID     string           `json:"campaign_id,omitempty"`
+	ClickID        string           `json:"click_id,omitempty"`
+	ConversionDate string           `json:"conversion_date"`
+	Value          *decimal.Decimal `json:"value,omitempty"`
+	Type           string           `json:"type"`
+	Source         string           `json:"source"`
+	OrderID        string           `json:"order_id,omitempty"`
+	TransactionID  string           `json:"transaction_id,omitempty"`
 }
 
 type ConversionEventResponse struct {
@@ -48,75 +58,118 @@ func (h *ConversionEventHandler) CreateConversionEvent(w http.ResponseWriter, r
 		return
 	}
 
-	userID, err := uuid.Parse(req.UserID)
+	if req.ConversionID == "" {
+		req.ConversionID = idempotentEventID(r, batchEventTypeConversion)
+	}
+
+	conversionEvent, err := buildConversionEvent(req, h.crossCampaign)
 	if err != nil {
-		http.Error(w, "Invalid user_id format", http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	campaignID, err := uuid.Parse(req.CampaignID)
-	if err != nil {
-		http.Error(w, "Invalid campaign_id format", http.StatusBadRequest)
+	if err := h.conversionEventPub.PublishConversionEvent(conversionEvent); err != nil {
+		http.Error(w, "Failed to create conversion event", http.StatusInternalServerError)
 		return
 	}
 
+	response := ConversionEventResponse{
+		ConversionID: conversionEvent.ConversionID.String(),
+		Message:      "Conversion event created successfully",
+		Status:       "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// buildConversionEvent validates a conversion request and maps it to the event
+// that is published to Kafka. The returned error message is safe to show to clients.
+// campaign_id may only be left out when crossCampaign is set.
+func buildConversionEvent(req ConversionEventRequest, crossCampaign bool) (publisher.ConversionEvent, error) {
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		return publisher.ConversionEvent{}, errors.New("Invalid user_id format")
+	}
+
+	// Under cross-campaign attribution campaign_id is optional: the conversion
+	// is assigned to the campaign of the credited click. Otherwise clicks are
+	// only looked up within the conversion's campaign, so it is required.
+	var campaignID uuid.UUID
+	if req.CampaignID == "" && !crossCampaign {
+		return publisher.ConversionEvent{}, errors.New("campaign_id is required")
+	}
+	if req.CampaignID != "" {
+		campaignID, err = uuid.Parse(req.CampaignID)
+		if err != nil {
+			return publisher.ConversionEvent{}, errors.New("Invalid campaign_id format")
+		}
+	}
+
+	var clickID *uuid.UUID
+	if req.ClickID != "" {
+		parsedClickID, err := uuid.Parse(req.ClickID)
+		if err != nil {
+			return publisher.ConversionEvent{}, errors.New("Invalid click_id format")
+		}
+		clickID = &parsedClickID
+	}
+
 	conversionDate, err := time.Parse(time.RFC3339, req.ConversionDate)
 	if err != nil {
-		http.Error(w, "Invalid conversion_date format, use RFC3339", http.StatusBadRequest)
-		return
+		return publisher.ConversionEvent{}, errors.New("Invalid conversion_date format, use RFC3339")
 	}
 
 	if req.Type == "" {
-		http.Error(w, "Type is required", http.StatusBadRequest)
-		return
+		return publisher.ConversionEvent{}, errors.New("Type is required")
 	}
 
 	if req.Source == "" {
-		http.Error(w, "Source is required", http.StatusBadRequest)
-		return
+		return publisher.ConversionEvent{}, errors.New("Source is required")
 	}
 
 	var conversionID uuid.UUID
 	if req.ConversionID != "" {
-		var err error
 		conversionID, err = uuid.Parse(req.ConversionID)
 		if err != nil {
-			http.Error(w, "Invalid conversion_id format", http.StatusBadRequest)
-			return
+			return publisher.ConversionEvent{}, errors.New("Invalid conversion_id format")
 		}
 	} else {
 		conversionID = uuid.New()
 	}
 
+	// transaction_id is accepted as an alias for order_id, which wins when
+	// both are sent.
+	var orderID *string
+	if req.OrderID != "" {
+		orderID = &req.OrderID
+	} else if req.TransactionID != "" {
+		orderID = &req.TransactionID
+	}
+
+	if orderID != nil && len(*orderID) > 255 {
+		return publisher.ConversionEvent{}, errors.New("order_id must be at most 255 characters")
+	}
+
 	var value *decimal.Decimal
-	if req.Value != 0 {
-		val := decimal.NewFromFloat(req.Value)
-		value = &val
+	if req.Value != nil && !req.Value.IsZero() {
+		if req.Value.Abs().GreaterThanOrEqual(maxConversionValue) {
+			return publisher.ConversionEvent{}, errors.New("value must be less than 100000000")
+		}
+		value = req.Value
 	}
 
-	conversionEvent := publisher.ConversionEvent{
+	return publisher.ConversionEvent{
 		ConversionID:   conversionID,
 		UserID:         userID,
 		CampaignID:     campaignID,
+		ClickID:        clickID,
 		ConversionDate: conversionDate,
 		Value:          value,
 		Type:           req.Type,
 		Source:         req.Source,
+		OrderID:        orderID,
 		CreatedAt:      time.Now(),
-	}
-
-	if err := h.conversionEventPub.PublishConversionEvent(conversionEvent); err != nil {
-		http.Error(w, "Failed to create conversion event", http.StatusInternalServerError)
-		return
-	}
-
-	response := ConversionEventResponse{
-		ConversionID: conversionEvent.ConversionID.String(),
-		Message:      "Conversion event created successfully",
-		Status:       "success",
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+	}, nil
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// transparentGIF is a 1x1 transparent GIF returned by the conversion pixel.
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackConversionPixel handles GET /px/conversion. It reads the conversion from
+// query parameters, falling back to the tracking cookies set by tracked links
+// for user_id and click_id, and always answers with a transparent GIF.
+func (h *ConversionEventHandler) TrackConversionPixel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := http.StatusBadRequest
+	if req, err := conversionRequestFromQuery(r.URL.Query()); err == nil {
+		if req.UserID == "" {
+			if cookie, cookieErr := r.Cookie(userIDCookieName); cookieErr == nil {
+				req.UserID = cookie.Value
+			}
+		}
+
+		if req.ClickID == "" {
+			if cookie, cookieErr := r.Cookie(clickIDCookieName); cookieErr == nil {
+				req.ClickID = cookie.Value
+			}
+		}
+
+		status = h.publishConversionRequest(req)
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate")
+	w.WriteHeader(status)
+	w.Write(transparentGIF)
+}
+
+// HandlePostback handles GET /postback, the server-to-server variant of the
+// conversion endpoint for platforms that can only call a URL.
+func (h *ConversionEventHandler) HandlePostback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req, err := conversionRequestFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ConversionID == "" {
+		req.ConversionID = idempotentEventID(r, batchEventTypeConversion)
+	}
+
+	conversionEvent, err := buildConversionEvent(req, h.crossCampaign)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.conversionEventPub.PublishConversionEvent(conversionEvent); err != nil {
+		http.Error(w, "Failed to create conversion event", http.StatusInternalServerError)
+		return
+	}
+
+	response := ConversionEventResponse{
+		ConversionID: conversionEvent.ConversionID.String(),
+		Message:      "Conversion event created successfully",
+		Status:       "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ConversionEventHandler) publishConversionRequest(req ConversionEventRequest) int {
+	conversionEvent, err := buildConversionEvent(req, h.crossCampaign)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+
+	if err := h.conversionEventPub.PublishConversionEvent(conversionEvent); err != nil {
+		return http.StatusInternalServerError
+	}
+
+	return http.StatusOK
+}
+
+// conversionRequestFromQuery maps query parameters onto a ConversionEventRequest.
+// conversion_date defaults to now, since pixels and postbacks fire as the
+// conversion happens.
+func conversionRequestFromQuery(query url.Values) (ConversionEventRequest, error) {
+	req := ConversionEventRequest{
+		ConversionID:   query.Get("conversion_id"),
+		UserID:         query.Get("user_id"),
+		CampaignID:     query.Get("campaign_id"),
+		ClickID:        query.Get("click_id"),
+		ConversionDate: query.Get("conversion_date"),
+		Type:           query.Get("type"),
+		Source:         query.Get("source"),
+		OrderID:        query.Get("order_id"),
+		TransactionID:  query.Get("transaction_id"),
+	}
+
+	if req.ConversionDate == "" {
+		req.ConversionDate = time.Now().Format(time.RFC3339)
+	}
+
+	if valueStr := query.Get("value"); valueStr != "" {
+		value, err := decimal.NewFromString(valueStr)
+		if err != nil {
+			return ConversionEventRequest{}, errors.New("Invalid value format")
+		}
+		req.Value = &value
+	}
+
+	return req, nil
+}
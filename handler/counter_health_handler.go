@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"tyrattribution/counter"
+)
+
+type CounterHealthHandler struct {
+	counterStore *counter.ResilientStore
+}
+
+func NewCounterHealthHandler(counterStore *counter.ResilientStore) *CounterHealthHandler {
+	return &CounterHealthHandler{
+		counterStore: counterStore,
+	}
+}
+
+// GetCounterHealth reports whether Redis is reachable and how many counter
+// updates are buffered in Postgres. A degraded status still answers 200, since
+// events keep being counted.
+func (h *CounterHealthHandler) GetCounterHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	health, err := h.counterStore.Health(r.Context())
+	if err != nil {
+		http.Error(w, "Failed to get counter health: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(health)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"tyrattribution/consumer"
+)
+
+type DeadLetterHandler struct {
+	redriver *consumer.DeadLetterRedriver
+}
+
+func NewDeadLetterHandler(redriver *consumer.DeadLetterRedriver) *DeadLetterHandler {
+	return &DeadLetterHandler{
+		redriver: redriver,
+	}
+}
+
+type RedriveDeadLettersResponse struct {
+	Redriven int    `json:"redriven"`
+	Skipped  int    `json:"skipped"`
+	Message  string `json:"message"`
+	Status   string `json:"status"`
+}
+
+// RedriveDeadLetters publishes dead letters back onto their original topics.
+// The optional limit query parameter caps how many are moved in one call.
+func (h *DeadLetterHandler) RedriveDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit < 0 {
+			http.Error(w, "Invalid limit, must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsedLimit
+	}
+
+	result, err := h.redriver.Redrive(r.Context(), limit)
+	if err != nil {
+		http.Error(w, "Failed to redrive dead letters: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response := RedriveDeadLettersResponse{
+		Redriven: result.Redriven,
+		Skipped:  result.Skipped,
+		Message:  "Dead letters re-driven successfully",
+		Status:   "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
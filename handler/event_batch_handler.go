@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"tyrattribution/publisher"
+)
+
+const (
+	batchEventTypeClick      = "click"
+	batchEventTypeConversion = "conversion"
+
+	maxBatchLineBytes = 1 << 20
+	maxBatchBodyBytes = 64 << 20
+)
+
+type EventBatchHandler struct {
+	clickEventPub      publisher.ClickEventPublisher
+	conversionEventPub publisher.ConversionEventPublisher
+	maxEvents          int
+	crossCampaign      bool
+}
+
+func NewEventBatchHandler(clickEventPub publisher.ClickEventPublisher, conversionEventPub publisher.ConversionEventPublisher, maxEvents int, crossCampaign bool) *EventBatchHandler {
+	return &EventBatchHandler{
+		clickEventPub:      clickEventPub,
+		conversionEventPub: conversionEventPub,
+		maxEvents:          maxEvents,
+		crossCampaign:      crossCampaign,
+	}
+}
+
+type EventBatchResult struct {
+	Index     int    `json:"index"`
+	EventType string `json:"event_type,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type EventBatchResponse struct {
+	Accepted int                `json:"accepted"`
+	Rejected int                `json:"rejected"`
+	Results  []EventBatchResult `json:"results"`
+}
+
+type batchEventEnvelope struct {
+	EventType string `json:"event_type"`
+}
+
+type batchLine struct {
+	index int
+	raw   json.RawMessage
+}
+
+// CreateEventBatch accepts either NDJSON (one event per line) or a JSON array
+// of click and conversion events, each tagged with an event_type. Every event
+// is validated on its own and the valid ones are published in one producer
+// batch per topic.
+func (h *EventBatchHandler) CreateEventBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// With an Idempotency-Key, IDs are derived per line index so that
+	// resending the same batch yields the same events.
+	lines, err := readBatchLines(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if len(lines) == 0 {
+		http.Error(w, "Batch is empty", http.StatusBadRequest)
+		return
+	}
+
+	if len(lines) > h.maxEvents {
+		http.Error(w, fmt.Sprintf("Batch exceeds the maximum of %d events", h.maxEvents), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	results := make([]EventBatchResult, len(lines))
+	var clickEvents []publisher.ClickEvent
+	var clickResults []int
+	var conversionEvents []publisher.ConversionEvent
+	var conversionResults []int
+
+	for i, line := range lines {
+		results[i] = EventBatchResult{Index: line.index}
+
+		var envelope batchEventEnvelope
+		if err := json.Unmarshal(line.raw, &envelope); err != nil {
+			results[i].Status = "error"
+			results[i].Error = "Invalid JSON"
+			continue
+		}
+		results[i].EventType = envelope.EventType
+
+		switch envelope.EventType {
+		case batchEventTypeClick:
+			var req ClickEventRequest
+			if err := json.Unmarshal(line.raw, &req); err != nil {
+				results[i].Status = "error"
+				results[i].Error = "Invalid click event"
+				continue
+			}
+
+			if req.ClickID == "" {
+				req.ClickID = idempotentEventID(r, fmt.Sprintf("%s:%d", batchEventTypeClick, line.index))
+			}
+
+			clickEvent, err := buildClickEvent(req)
+			if err != nil {
+				results[i].Status = "error"
+				results[i].Error = err.Error()
+				continue
+			}
+
+			results[i].ID = clickEvent.ClickID.String()
+			clickEvents = append(clickEvents, clickEvent)
+			clickResults = append(clickResults, i)
+
+		case batchEventTypeConversion:
+			var req ConversionEventRequest
+			if err := json.Unmarshal(line.raw, &req); err != nil {
+				results[i].Status = "error"
+				results[i].Error = "Invalid conversion event"
+				continue
+			}
+
+			if req.ConversionID == "" {
+				req.ConversionID = idempotentEventID(r, fmt.Sprintf("%s:%d", batchEventTypeConversion, line.index))
+			}
+
+			conversionEvent, err := buildConversionEvent(req, h.crossCampaign)
+			if err != nil {
+				results[i].Status = "error"
+				results[i].Error = err.Error()
+				continue
+			}
+
+			results[i].ID = conversionEvent.ConversionID.String()
+			conversionEvents = append(conversionEvents, conversionEvent)
+			conversionResults = append(conversionResults, i)
+
+		default:
+			results[i].Status = "error"
+			results[i].Error = "event_type must be click or conversion"
+		}
+	}
+
+	if len(clickEvents) > 0 {
+		for j, err := range h.clickEventPub.PublishClickEvents(clickEvents) {
+			applyPublishResult(&results[clickResults[j]], err)
+		}
+	}
+
+	if len(conversionEvents) > 0 {
+		for j, err := range h.conversionEventPub.PublishConversionEvents(conversionEvents) {
+			applyPublishResult(&results[conversionResults[j]], err)
+		}
+	}
+
+	response := EventBatchResponse{Results: results}
+	for _, result := range results {
+		if result.Status == "success" {
+			response.Accepted++
+		} else {
+			response.Rejected++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func applyPublishResult(result *EventBatchResult, err error) {
+	if err != nil {
+		result.Status = "error"
+		result.Error = "Failed to publish event"
+		return
+	}
+	result.Status = "success"
+}
+
+// readBatchLines splits the body into raw events. A body starting with '[' is
+// decoded as a JSON array indexed by element; anything else is NDJSON indexed
+// by line number, with blank lines skipped.
+func readBatchLines(body io.Reader) ([]batchLine, error) {
+	reader := bufio.NewReader(body)
+	first, err := peekFirstNonSpace(reader)
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []batchLine
+
+	if first == '[' {
+		var items []json.RawMessage
+		if err := json.NewDecoder(reader).Decode(&items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			lines = append(lines, batchLine{index: i, raw: item})
+		}
+		return lines, nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBatchLineBytes)
+	index := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) > 0 {
+			raw := make(json.RawMessage, len(line))
+			copy(raw, line)
+			lines = append(lines, batchLine{index: index, raw: raw})
+		}
+		index++
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+func peekFirstNonSpace(reader *bufio.Reader) (byte, error) {
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			return b, reader.UnreadByte()
+		}
+	}
+}
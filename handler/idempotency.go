@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotencyNamespace scopes the event IDs derived from Idempotency-Key headers.
+var idempotencyNamespace = uuid.MustParse("2cbafd09-f616-4bbe-a176-47663ebf3b14")
+
+// idempotentEventID derives a stable event ID from the request's
+// Idempotency-Key header, so a retried request publishes the same event ID and
+// the consumers store and count it only once. The scope keeps IDs for
+// different event types, or different lines of a batch, apart. It returns an
+// empty string when the request has no key.
+func idempotentEventID(r *http.Request, scope string) string {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return ""
+	}
+
+	return uuid.NewSHA1(idempotencyNamespace, []byte(scope+":"+key)).String()
+}
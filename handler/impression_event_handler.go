@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"tyrattribution/publisher"
+
+	"github.com/google/uuid"
+)
+
+type ImpressionEventHandler struct {
+	impressionEventPub publisher.ImpressionEventPublisher
+}
+
+func NewImpressionEventHandler(impressionEventPub publisher.ImpressionEventPublisher) *ImpressionEventHandler {
+	return &ImpressionEventHandler{
+		impressionEventPub: impressionEventPub,
+	}
+}
+
+type ImpressionEventRequest struct {
+	ImpressionID   string `json:"impression_id,omitempty"`
+	CampaignID     string `json:"campaign_id"`
+	UserID         string `json:"user_id"`
+	ImpressionDate string `json:"impression_date"`
+	Source         string `json:"source"`
+}
+
+type ImpressionEventResponse struct {
+	ImpressionID string `json:"impression_id"`
+	Message      string `json:"message"`
+	Status       string `json:"status"`
+}
+
+func (h *ImpressionEventHandler) CreateImpressionEvent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req ImpressionEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.ImpressionID == "" {
+		req.ImpressionID = idempotentEventID(r, "impression")
+	}
+
+	campaignID, err := uuid.Parse(req.CampaignID)
+	if err != nil {
+		http.Error(w, "Invalid campaign_id format", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := uuid.Parse(req.UserID)
+	if err != nil {
+		http.Error(w, "Invalid user_id format", http.StatusBadRequest)
+		return
+	}
+
+	impressionDate, err := time.Parse(time.RFC3339, req.ImpressionDate)
+	if err != nil {
+		http.Error(w, "Invalid impression_date format, use RFC3339", http.StatusBadRequest)
+		return
+	}
+
+	if req.Source == "" {
+		http.Error(w, "Source is required", http.StatusBadRequest)
+		return
+	}
+
+	var impressionID uuid.UUID
+	if req.ImpressionID != "" {
+		impressionID, err = uuid.Parse(req.ImpressionID)
+		if err != nil {
+			http.Error(w, "Invalid impression_id format", http.StatusBadRequest)
+			return
+		}
+	} else {
+		impressionID = uuid.New()
+	}
+
+	impressionEvent := publisher.ImpressionEvent{
+		ImpressionID:   impressionID,
+		CampaignID:     campaignID,
+		UserID:         userID,
+		ImpressionDate: impressionDate,
+		Source:         req.Source,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := h.impressionEventPub.PublishImpressionEvent(impressionEvent); err != nil {
+		http.Error(w, "Failed to create impression event", http.StatusInternalServerError)
+		return
+	}
+
+	response := ImpressionEventResponse{
+		ImpressionID: impressionEvent.ImpressionID.String(),
+		Message:      "Impression event created successfully",
+		Status:       "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tyrattribution/service"
+)
+
+type ReconciliationHandler struct {
+	reconciliationService service.ReconciliationService
+}
+
+func NewReconciliationHandler(reconciliationService service.ReconciliationService) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reconciliationService: reconciliationService,
+	}
+}
+
+type ReconcileResponse struct {
+	Report  *service.ReconciliationReport `json:"report"`
+	Message string                        `json:"message"`
+	Status  string                        `json:"status"`
+}
+
+// Reconcile compares the Redis counters and campaign journal with the event
+// tables for the days from the from query parameter to the optional to
+// parameter, both YYYY-MM-DD. With repair=true the discrepancies are fixed.
+func (h *ReconciliationHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fromStr := r.URL.Query().Get("from")
+	if fromStr == "" {
+		http.Error(w, "from parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", fromStr, time.Local)
+	if err != nil {
+		http.Error(w, "Invalid from format, use YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	to := from
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		to, err = time.ParseInLocation("2006-01-02", toStr, time.Local)
+		if err != nil {
+			http.Error(w, "Invalid to format, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	repair := false
+	if repairStr := r.URL.Query().Get("repair"); repairStr != "" {
+		repair, err = strconv.ParseBool(repairStr)
+		if err != nil {
+			http.Error(w, "Invalid repair, must be true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.reconciliationService.Reconcile(r.Context(), from, to, repair)
+	if err != nil {
+		http.Error(w, "Failed to reconcile metrics: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	message := "Metrics reconciled, no discrepancies found"
+	if len(report.Discrepancies) > 0 {
+		message = "Metrics reconciled, discrepancies found"
+		if repair {
+			message = "Metrics reconciled, discrepancies repaired"
+		}
+	}
+
+	response := ReconcileResponse{
+		Report:  report,
+		Message: message,
+		Status:  "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	"tyrattribution/config"
+	"tyrattribution/entity"
+	"tyrattribution/publisher"
+	"tyrattribution/service"
+
+	"github.com/google/uuid"
+)
+
+const (
+	userIDCookieName  = "tyr_uid"
+	clickIDCookieName = "tyr_click_id"
+	clickIDQueryParam = "click_id"
+
+	userIDCookieMaxAge = 365 * 24 * time.Hour
+)
+
+// linkIDPattern limits client-supplied link IDs to characters that can be
+// used as-is in the /r/{link_id} path.
+var linkIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+type TrackedLinkHandler struct {
+	trackedLinkService     service.TrackedLinkService
+	campaignSettingService service.CampaignSettingService
+	clickEventPub          publisher.ClickEventPublisher
+	config                 *config.Config
+}
+
+func NewTrackedLinkHandler(trackedLinkService service.TrackedLinkService, campaignSettingService service.CampaignSettingService, clickEventPub publisher.ClickEventPublisher, cfg *config.Config) *TrackedLinkHandler {
+	return &TrackedLinkHandler{
+		trackedLinkService:     trackedLinkService,
+		campaignSettingService: campaignSettingService,
+		clickEventPub:          clickEventPub,
+		config:                 cfg,
+	}
+}
+
+type TrackedLinkRequest struct {
+	LinkID         string `json:"link_id,omitempty"`
+	CampaignID     string `json:"campaign_id"`
+	Source         string `json:"source"`
+	DestinationURL string `json:"destination_url"`
+}
+
+type TrackedLinkResponse struct {
+	LinkID  string `json:"link_id"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+func (h *TrackedLinkHandler) CreateTrackedLink(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req TrackedLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	campaignID, err := uuid.Parse(req.CampaignID)
+	if err != nil {
+		http.Error(w, "Invalid campaign_id format", http.StatusBadRequest)
+		return
+	}
+
+	if req.Source == "" {
+		http.Error(w, "Source is required", http.StatusBadRequest)
+		return
+	}
+
+	destinationURL, err := url.Parse(req.DestinationURL)
+	if err != nil || (destinationURL.Scheme != "http" && destinationURL.Scheme != "https") || destinationURL.Host == "" {
+		http.Error(w, "Invalid destination_url, use an absolute http or https URL", http.StatusBadRequest)
+		return
+	}
+
+	if req.LinkID != "" && !linkIDPattern.MatchString(req.LinkID) {
+		http.Error(w, "link_id must be at most 64 letters, digits, underscores or hyphens", http.StatusBadRequest)
+		return
+	}
+
+	trackedLink := &entity.TrackedLink{
+		LinkID:         req.LinkID,
+		CampaignID:     campaignID,
+		Source:         req.Source,
+		DestinationURL: destinationURL.String(),
+	}
+
+	err = h.trackedLinkService.CreateTrackedLink(r.Context(), trackedLink)
+	if errors.Is(err, service.ErrTrackedLinkExists) {
+		http.Error(w, "link_id is already in use", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to create tracked link", http.StatusInternalServerError)
+		return
+	}
+
+	response := TrackedLinkResponse{
+		LinkID:  trackedLink.LinkID,
+		Message: "Tracked link created successfully",
+		Status:  "success",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Redirect records a click for the tracked link and forwards the user to its
+// destination. The user is identified by a first-party cookie, minted on the
+// first visit. A failed publish is logged but never blocks the redirect.
+func (h *TrackedLinkHandler) Redirect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	trackedLink, err := h.trackedLinkService.GetTrackedLink(r.Context(), r.PathValue("link_id"))
+	if errors.Is(err, service.ErrTrackedLinkNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to resolve tracked link", http.StatusInternalServerError)
+		return
+	}
+
+	destinationURL, err := url.Parse(trackedLink.DestinationURL)
+	if err != nil {
+		http.Error(w, "Invalid tracked link destination", http.StatusInternalServerError)
+		return
+	}
+
+	userID := h.resolveUserID(w, r)
+	now := time.Now()
+
+	clickEvent := publisher.ClickEvent{
+		ClickID:    uuid.New(),
+		CampaignID: trackedLink.CampaignID,
+		UserID:     userID,
+		ClickDate:  now,
+		Source:     trackedLink.Source,
+		CreatedAt:  now,
+	}
+
+	if err := h.clickEventPub.PublishClickEvent(clickEvent); err != nil {
+		log.Printf("Failed to publish click for tracked link %s: %v", trackedLink.LinkID, err)
+	} else {
+		http.SetCookie(w, h.newCookie(clickIDCookieName, clickEvent.ClickID.String(), h.clickIDCookieMaxAge(r, trackedLink)))
+
+		if h.config.TrackedLinkAppendClickID {
+			query := destinationURL.Query()
+			query.Set(clickIDQueryParam, clickEvent.ClickID.String())
+			destinationURL.RawQuery = query.Encode()
+		}
+	}
+
+	http.Redirect(w, r, destinationURL.String(), http.StatusFound)
+}
+
+// clickIDCookieMaxAge keeps the click cookie for as long as the link's
+// campaign can still credit the click.
+func (h *TrackedLinkHandler) clickIDCookieMaxAge(r *http.Request, trackedLink *entity.TrackedLink) time.Duration {
+	settings, err := h.campaignSettingService.GetAttributionSettings(r.Context(), trackedLink.CampaignID)
+	if err != nil {
+		log.Printf("Failed to get attribution settings for campaign %s, using the default look-back window: %v", trackedLink.CampaignID.String(), err)
+		settings = h.campaignSettingService.DefaultAttributionSettings()
+	}
+
+	return time.Duration(settings.LookbackWindowHours) * time.Hour
+}
+
+func (h *TrackedLinkHandler) resolveUserID(w http.ResponseWriter, r *http.Request) uuid.UUID {
+	if cookie, err := r.Cookie(userIDCookieName); err == nil {
+		if userID, err := uuid.Parse(cookie.Value); err == nil {
+			return userID
+		}
+	}
+
+	userID := uuid.New()
+	http.SetCookie(w, h.newCookie(userIDCookieName, userID.String(), userIDCookieMaxAge))
+	return userID
+}
+
+func (h *TrackedLinkHandler) newCookie(name string, value string, maxAge time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   h.config.TrackingCookieDomain,
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   h.config.TrackingCookieSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,93 @@
+package kafka
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"tyrattribution/config"
+
+	"github.com/IBM/sarama"
+)
+
+// NewConfig returns the sarama settings shared by every Kafka producer and
+// consumer: the client ID and, when configured, SASL authentication and TLS.
+func NewConfig(cfg *config.Config) (*sarama.Config, error) {
+	saramaConfig := sarama.NewConfig()
+	saramaConfig.ClientID = "tyrattribution"
+
+	if err := configureSASL(saramaConfig, cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.KafkaTLSEnabled {
+		tlsConfig, err := newTLSConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		saramaConfig.Net.TLS.Enable = true
+		saramaConfig.Net.TLS.Config = tlsConfig
+	}
+
+	return saramaConfig, nil
+}
+
+func configureSASL(saramaConfig *sarama.Config, cfg *config.Config) error {
+	switch cfg.KafkaSASLMechanism {
+	case "", "none":
+		return nil
+	case "PLAIN":
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+	case "SCRAM-SHA-256":
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: sha256.New}
+		}
+	case "SCRAM-SHA-512":
+		saramaConfig.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+		saramaConfig.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{hashGenerator: sha512.New}
+		}
+	default:
+		return fmt.Errorf("unknown Kafka SASL mechanism %q, use PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", cfg.KafkaSASLMechanism)
+	}
+
+	saramaConfig.Net.SASL.Enable = true
+	saramaConfig.Net.SASL.Handshake = true
+	saramaConfig.Net.SASL.User = cfg.KafkaSASLUsername
+	saramaConfig.Net.SASL.Password = cfg.KafkaSASLPassword
+
+	return nil
+}
+
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.KafkaTLSSkipVerify,
+	}
+
+	if cfg.KafkaTLSCAFile != "" {
+		caCert, err := os.ReadFile(cfg.KafkaTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Kafka CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no certificates found in Kafka CA file %s", cfg.KafkaTLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.KafkaTLSCertFile != "" || cfg.KafkaTLSKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(cfg.KafkaTLSCertFile, cfg.KafkaTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Kafka client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
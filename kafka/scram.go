@@ -0,0 +1,31 @@
+package kafka
+
+import (
+	"github.com/xdg-go/scram"
+)
+
+// scramClient adapts the xdg-go SCRAM client (RFC 5802, RFC 7677) to the
+// interface sarama runs for the SCRAM-SHA-256 and SCRAM-SHA-512 mechanisms.
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+
+	conversation *scram.ClientConversation
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	"tyrattribution/attribution"
 	"tyrattribution/config"
 	"tyrattribution/consumer"
+	"tyrattribution/counter"
 	"tyrattribution/database"
 	"tyrattribution/publisher"
 	"tyrattribution/redis"
@@ -24,6 +27,13 @@ func main() {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := runReplay(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Replay failed: %v", err)
+		}
+		return
+	}
+
 	db, err := database.OpenDatabase(cfg)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
@@ -35,33 +45,80 @@ func main() {
 	}
 
 	clickEventRepo := repository.NewClickEventRepository(db)
+	impressionEventRepo := repository.NewImpressionEventRepository(db)
 	conversionEventRepo := repository.NewConversionEventRepository(db)
 	campaignRepo := repository.NewCampaignRepository(db)
 	campaignJournalRepo := repository.NewCampaignJournalRepository(db)
 	campaignStatsRepo := repository.NewCampaignStatisticsRepository(db)
+	campaignSettingRepo := repository.NewCampaignSettingRepository(db)
+	trackedLinkRepo := repository.NewTrackedLinkRepository(db)
+	outboxEventRepo := repository.NewOutboxEventRepository(db)
+	pendingAttributionRepo := repository.NewPendingAttributionRepository(db)
+	counterBufferRepo := repository.NewCounterBufferRepository(db)
 
-	clickEventService := service.NewClickEventService(clickEventRepo, redisClient)
-	conversionEventService := service.NewConversionEventService(conversionEventRepo, clickEventService, redisClient, cfg)
-	campaignJournalService := service.NewCampaignJournalService(campaignJournalRepo, campaignRepo, clickEventRepo, conversionEventRepo, redisClient)
-	campaignStatisticsService := service.NewCampaignStatisticsService(campaignJournalRepo, campaignStatsRepo, redisClient)
+	// Counter updates are buffered in Postgres while Redis is unavailable.
+	counterStore := counter.NewResilientStore(cfg, counter.NewRedisStore(redisClient), counterBufferRepo)
 
-	clickEventPublisher, err := publisher.NewClickEventPublisher(cfg)
+	attributionModel, err := attribution.NewModel(cfg.AttributionModel, cfg.AttributionHalfLifeHours)
 	if err != nil {
-		log.Fatalf("Failed to create click event publisher: %v", err)
+		log.Fatalf("Failed to create attribution model: %v", err)
 	}
 
-	conversionEventPublisher, err := publisher.NewConversionEventPublisher(cfg)
+	campaignSettingService := service.NewCampaignSettingService(campaignSettingRepo, attributionModel, cfg)
+	clickEventService := service.NewClickEventService(clickEventRepo, pendingAttributionRepo, counterStore)
+	impressionEventService := service.NewImpressionEventService(impressionEventRepo, counterStore)
+	conversionEventService := service.NewConversionEventService(conversionEventRepo, pendingAttributionRepo, campaignJournalRepo, clickEventService, impressionEventService, campaignSettingService, counterStore, cfg)
+	campaignJournalService := service.NewCampaignJournalService(campaignJournalRepo, campaignRepo, clickEventRepo, conversionEventRepo, counterStore)
+	reconciliationService := service.NewReconciliationService(campaignJournalRepo, campaignJournalService, counterStore)
+	campaignStatisticsService := service.NewCampaignStatisticsService(campaignJournalRepo, campaignStatsRepo, counterStore)
+	trackedLinkService := service.NewTrackedLinkService(trackedLinkRepo)
+
+	if err := publisher.ValidatePartitionKey(cfg.KafkaPartitionKey); err != nil {
+		log.Fatalf("Invalid event partition key: %v", err)
+	}
+
+	transportProducer, subscriber, err := openEventTransport(cfg)
 	if err != nil {
-		log.Fatalf("Failed to create conversion event publisher: %v", err)
+		log.Fatalf("Failed to open event transport: %v", err)
 	}
 
-	mux := routes.SetupRoutes(clickEventPublisher, conversionEventPublisher, campaignJournalService, campaignStatisticsService)
+	// With the outbox enabled, handlers only write to Postgres and the relay
+	// started below forwards the events to the transport. In async Kafka mode
+	// handlers only queue the events, and failed deliveries go to the outbox.
+	eventProducer := transportProducer
+	asyncProducerEnabled := cfg.EventTransport == "kafka" && cfg.KafkaProducerMode == "async"
+	switch {
+	case cfg.OutboxEnabled:
+		eventProducer = publisher.NewOutboxProducer(outboxEventRepo)
+	case asyncProducerEnabled:
+		eventProducer, err = publisher.NewKafkaAsyncProducer(cfg, publisher.NewOutboxProducer(outboxEventRepo))
+		if err != nil {
+			log.Fatalf("Failed to create Kafka async producer: %v", err)
+		}
+	}
+
+	clickEventPublisher := publisher.NewClickEventPublisher(cfg, eventProducer)
+	conversionEventPublisher := publisher.NewConversionEventPublisher(cfg, eventProducer)
+	impressionEventPublisher := publisher.NewImpressionEventPublisher(cfg, eventProducer)
+	failedMessagePublisher := publisher.NewFailedMessagePublisher(cfg, transportProducer)
+
+	deadLetterRedriver := consumer.NewDeadLetterRedriver(cfg, subscriber, failedMessagePublisher)
+
+	mux := routes.SetupRoutes(clickEventPublisher, conversionEventPublisher, impressionEventPublisher, campaignJournalService, reconciliationService, campaignStatisticsService, campaignSettingService, trackedLinkService, deadLetterRedriver, counterStore, cfg)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	go consumer.StartClickEventConsumer(ctx, cfg, clickEventService)
-	go consumer.StartConversionEventConsumer(ctx, cfg, conversionEventService)
+	if cfg.OutboxEnabled || asyncProducerEnabled {
+		go publisher.StartOutboxRelay(ctx, cfg, outboxEventRepo, transportProducer)
+	}
+
+	go consumer.StartClickEventConsumer(ctx, cfg, subscriber, clickEventService, failedMessagePublisher)
+	go consumer.StartConversionEventConsumer(ctx, cfg, subscriber, conversionEventService, failedMessagePublisher)
+	go consumer.StartImpressionEventConsumer(ctx, cfg, subscriber, impressionEventService, failedMessagePublisher)
+	go consumer.StartReattributionWorker(ctx, cfg, conversionEventService)
+	go counter.StartCounterFlusher(ctx, counterStore)
+	go consumer.StartRetryConsumer(ctx, cfg, subscriber, clickEventService, conversionEventService, impressionEventService, failedMessagePublisher)
 
 	server := &http.Server{
 		Addr:    ":8080",
@@ -92,5 +149,40 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Flush events still queued by the async producer.
+	if asyncProducerEnabled && !cfg.OutboxEnabled {
+		if err := eventProducer.Close(); err != nil {
+			log.Printf("Failed to close event producer: %v", err)
+		}
+	}
+
 	log.Println("Server exited")
 }
+
+// openEventTransport returns the producer and subscriber of the configured
+// event transport.
+func openEventTransport(cfg *config.Config) (publisher.Producer, consumer.Subscriber, error) {
+	switch cfg.EventTransport {
+	case "kafka":
+		producer, err := publisher.NewKafkaProducer(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		subscriber, err := consumer.NewKafkaSubscriber(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return producer, subscriber, nil
+	case "redis":
+		client, err := redis.NewStreamClient(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return publisher.NewRedisStreamProducer(client, cfg.RedisStreamMaxLen), consumer.NewRedisStreamSubscriber(client), nil
+	case "memory":
+		bus := publisher.NewMemoryBus(cfg.MemoryBusBufferSize)
+		return bus, consumer.NewMemorySubscriber(bus), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown event transport %q, use kafka, redis or memory", cfg.EventTransport)
+	}
+}
@@ -7,13 +7,22 @@ import (
 	"time"
 	"tyrattribution/config"
 
-	"github.com/IBM/sarama"
 	"github.com/google/uuid"
 )
 
-type ClickEventPublisher struct {
-	producer sarama.SyncProducer
-	topic    string
+// ClickEventPublisher publishes click events to the configured event transport.
+type ClickEventPublisher interface {
+	PublishClickEvent(event ClickEvent) error
+	// PublishClickEvents sends all events in a single producer batch. The
+	// returned slice has one entry per event, nil when that event was delivered.
+	PublishClickEvents(events []ClickEvent) []error
+	Close() error
+}
+
+type clickEventPublisher struct {
+	producer     Producer
+	topic        string
+	partitionKey string
 }
 
 type ClickEvent struct {
@@ -25,47 +34,75 @@ type ClickEvent struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
-func NewClickEventPublisher(cfg *config.Config) (*ClickEventPublisher, error) {
-	brokerURL := cfg.KafkaUrl
-	topic := cfg.KafkaClickTopic
-
-	config := sarama.NewConfig()
-	config.Producer.Return.Successes = true
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 3
-
-	producer, err := sarama.NewSyncProducer([]string{brokerURL}, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+func NewClickEventPublisher(cfg *config.Config, producer Producer) ClickEventPublisher {
+	return &clickEventPublisher{
+		producer:     producer,
+		topic:        cfg.KafkaClickTopic,
+		partitionKey: cfg.KafkaPartitionKey,
 	}
-
-	return &ClickEventPublisher{
-		producer: producer,
-		topic:    topic,
-	}, nil
 }
 
-func (p *ClickEventPublisher) PublishClickEvent(event ClickEvent) error {
+func (p *clickEventPublisher) PublishClickEvent(event ClickEvent) error {
 	eventJSON, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	message := &sarama.ProducerMessage{
+	message := Message{
 		Topic: p.topic,
-		Key:   sarama.StringEncoder(event.ClickID.String()),
-		Value: sarama.ByteEncoder(eventJSON),
+		Key:   messageKey(p.partitionKey, event.ClickID, event.UserID, event.CampaignID),
+		Value: eventJSON,
 	}
 
-	partition, offset, err := p.producer.SendMessage(message)
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+	if err := p.producer.SendMessage(message); err != nil {
+		return err
 	}
 
-	log.Printf("Click event published to partition %d at offset %d", partition, offset)
+	log.Printf("Click event %s published to %s", event.ClickID.String(), p.topic)
 	return nil
 }
 
-func (p *ClickEventPublisher) Close() error {
+func (p *clickEventPublisher) PublishClickEvents(events []ClickEvent) []error {
+	errs := make([]error, len(events))
+	messages := make([]Message, 0, len(events))
+	indexes := make([]int, 0, len(events))
+
+	for i, event := range events {
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to marshal event: %w", err)
+			continue
+		}
+
+		messages = append(messages, Message{
+			Topic: p.topic,
+			Key:   messageKey(p.partitionKey, event.ClickID, event.UserID, event.CampaignID),
+			Value: eventJSON,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if len(messages) == 0 {
+		return errs
+	}
+
+	failed := 0
+	for j, err := range p.producer.SendMessages(messages) {
+		if err != nil {
+			errs[indexes[j]] = err
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		log.Printf("Failed to publish %d of %d click events in batch", failed, len(messages))
+		return errs
+	}
+
+	log.Printf("Published batch of %d click events", len(messages))
+	return errs
+}
+
+func (p *clickEventPublisher) Close() error {
 	return p.producer.Close()
 }
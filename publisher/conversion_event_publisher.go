@@ -7,68 +7,107 @@ import (
 	"time"
 	"tyrattribution/config"
 
-	"github.com/IBM/sarama"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
-type ConversionEventPublisher struct {
-	producer sarama.SyncProducer
-	topic    string
+// ConversionEventPublisher publishes conversion events to the configured event transport.
+type ConversionEventPublisher interface {
+	PublishConversionEvent(event ConversionEvent) error
+	// PublishConversionEvents sends all events in a single producer batch. The
+	// returned slice has one entry per event, nil when that event was delivered.
+	PublishConversionEvents(events []ConversionEvent) []error
+	Close() error
+}
+
+type conversionEventPublisher struct {
+	producer     Producer
+	topic        string
+	partitionKey string
 }
 
 type ConversionEvent struct {
 	ConversionID   uuid.UUID        `json:"conversion_id"`
 	UserID         uuid.UUID        `json:"user_id"`
 	CampaignID     uuid.UUID        `json:"campaign_id"`
+	ClickID        *uuid.UUID       `json:"click_id,omitempty"`
 	ConversionDate time.Time        `json:"conversion_date"`
 	Value          *decimal.Decimal `json:"value"`
 	Type           string           `json:"type"`
 	Source         string           `json:"source"`
+	OrderID        *string          `json:"order_id,omitempty"`
 	CreatedAt      time.Time        `json:"created_at"`
 }
 
-func NewConversionEventPublisher(cfg *config.Config) (*ConversionEventPublisher, error) {
-	brokerURL := cfg.KafkaUrl
-	topic := cfg.KafkaConversionTopic
-
-	config := sarama.NewConfig()
-	config.Producer.Return.Successes = true
-	config.Producer.RequiredAcks = sarama.WaitForAll
-	config.Producer.Retry.Max = 3
-
-	producer, err := sarama.NewSyncProducer([]string{brokerURL}, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create producer: %w", err)
+func NewConversionEventPublisher(cfg *config.Config, producer Producer) ConversionEventPublisher {
+	return &conversionEventPublisher{
+		producer:     producer,
+		topic:        cfg.KafkaConversionTopic,
+		partitionKey: cfg.KafkaPartitionKey,
 	}
-
-	return &ConversionEventPublisher{
-		producer: producer,
-		topic:    topic,
-	}, nil
 }
 
-func (p *ConversionEventPublisher) PublishConversionEvent(event ConversionEvent) error {
+func (p *conversionEventPublisher) PublishConversionEvent(event ConversionEvent) error {
 	eventJSON, err := json.Marshal(event)
 	if err != nil {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	message := &sarama.ProducerMessage{
+	message := Message{
 		Topic: p.topic,
-		Key:   sarama.StringEncoder(event.ConversionID.String()),
-		Value: sarama.ByteEncoder(eventJSON),
+		Key:   messageKey(p.partitionKey, event.ConversionID, event.UserID, event.CampaignID),
+		Value: eventJSON,
 	}
 
-	partition, offset, err := p.producer.SendMessage(message)
-	if err != nil {
-		return fmt.Errorf("failed to send message: %w", err)
+	if err := p.producer.SendMessage(message); err != nil {
+		return err
 	}
 
-	log.Printf("Conversion event published to partition %d at offset %d", partition, offset)
+	log.Printf("Conversion event %s published to %s", event.ConversionID.String(), p.topic)
 	return nil
 }
 
-func (p *ConversionEventPublisher) Close() error {
+func (p *conversionEventPublisher) PublishConversionEvents(events []ConversionEvent) []error {
+	errs := make([]error, len(events))
+	messages := make([]Message, 0, len(events))
+	indexes := make([]int, 0, len(events))
+
+	for i, event := range events {
+		eventJSON, err := json.Marshal(event)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to marshal event: %w", err)
+			continue
+		}
+
+		messages = append(messages, Message{
+			Topic: p.topic,
+			Key:   messageKey(p.partitionKey, event.ConversionID, event.UserID, event.CampaignID),
+			Value: eventJSON,
+		})
+		indexes = append(indexes, i)
+	}
+
+	if len(messages) == 0 {
+		return errs
+	}
+
+	failed := 0
+	for j, err := range p.producer.SendMessages(messages) {
+		if err != nil {
+			errs[indexes[j]] = err
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		log.Printf("Failed to publish %d of %d conversion events in batch", failed, len(messages))
+		return errs
+	}
+
+	log.Printf("Published batch of %d conversion events", len(messages))
+	return errs
+}
+
+func (p *conversionEventPublisher) Close() error {
 	return p.producer.Close()
 }
@@ -0,0 +1,85 @@
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+	"tyrattribution/config"
+)
+
+// FailedMessagePublisher moves messages the consumers could not process onto
+// the retry and dead-letter topics, and re-drives dead letters back onto the
+// topic they came from.
+type FailedMessagePublisher struct {
+	producer        Producer
+	retryTopic      string
+	deadLetterTopic string
+}
+
+// FailedMessage wraps the original Kafka message with where it came from and
+// why it failed. Value is the untouched original payload, so poison messages
+// that are not valid JSON survive the round trip.
+type FailedMessage struct {
+	OriginalTopic string    `json:"original_topic"`
+	Partition     int32     `json:"partition"`
+	Offset        int64     `json:"offset"`
+	MessageID     string    `json:"message_id,omitempty"`
+	Key           []byte    `json:"key"`
+	Value         []byte    `json:"value"`
+	Error         string    `json:"error"`
+	Attempt       int       `json:"attempt"`
+	FailedAt      time.Time `json:"failed_at"`
+	RetryAt       time.Time `json:"retry_at,omitempty"`
+}
+
+func NewFailedMessagePublisher(cfg *config.Config, producer Producer) *FailedMessagePublisher {
+	return &FailedMessagePublisher{
+		producer:        producer,
+		retryTopic:      cfg.KafkaRetryTopic,
+		deadLetterTopic: cfg.KafkaDeadLetterTopic,
+	}
+}
+
+func (p *FailedMessagePublisher) PublishRetry(message FailedMessage) error {
+	return p.publish(p.retryTopic, message)
+}
+
+func (p *FailedMessagePublisher) PublishDeadLetter(message FailedMessage) error {
+	return p.publish(p.deadLetterTopic, message)
+}
+
+// Redrive publishes the original payload back onto its original topic, where
+// it is consumed as if it had never failed.
+func (p *FailedMessagePublisher) Redrive(message FailedMessage) error {
+	return p.producer.SendMessage(Message{
+		Topic: message.OriginalTopic,
+		Key:   message.Key,
+		Value: message.Value,
+	})
+}
+
+func (p *FailedMessagePublisher) publish(topic string, message FailedMessage) error {
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal failed message: %w", err)
+	}
+
+	producerMessage := Message{
+		Topic: topic,
+		Key:   message.Key,
+		Value: messageJSON,
+	}
+
+	if err := p.producer.SendMessage(producerMessage); err != nil {
+		return err
+	}
+
+	log.Printf("Failed message from %s/%d@%d (attempt %d) published to %s",
+		message.OriginalTopic, message.Partition, message.Offset, message.Attempt, topic)
+	return nil
+}
+
+func (p *FailedMessagePublisher) Close() error {
+	return p.producer.Close()
+}
@@ -0,0 +1,64 @@
+package publisher
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+	"tyrattribution/config"
+
+	"github.com/google/uuid"
+)
+
+// ImpressionEventPublisher publishes impression events to the configured event transport.
+type ImpressionEventPublisher interface {
+	PublishImpressionEvent(event ImpressionEvent) error
+	Close() error
+}
+
+type impressionEventPublisher struct {
+	producer     Producer
+	topic        string
+	partitionKey string
+}
+
+type ImpressionEvent struct {
+	ImpressionID   uuid.UUID `json:"impression_id"`
+	CampaignID     uuid.UUID `json:"campaign_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	ImpressionDate time.Time `json:"impression_date"`
+	Source         string    `json:"source"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func NewImpressionEventPublisher(cfg *config.Config, producer Producer) ImpressionEventPublisher {
+	return &impressionEventPublisher{
+		producer:     producer,
+		topic:        cfg.KafkaImpressionTopic,
+		partitionKey: cfg.KafkaPartitionKey,
+	}
+}
+
+func (p *impressionEventPublisher) PublishImpressionEvent(event ImpressionEvent) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	message := Message{
+		Topic: p.topic,
+		Key:   messageKey(p.partitionKey, event.ImpressionID, event.UserID, event.CampaignID),
+		Value: eventJSON,
+	}
+
+	if err := p.producer.SendMessage(message); err != nil {
+		return err
+	}
+
+	log.Printf("Impression event %s published to %s", event.ImpressionID.String(), p.topic)
+	return nil
+}
+
+func (p *impressionEventPublisher) Close() error {
+	return p.producer.Close()
+}
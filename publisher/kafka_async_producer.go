@@ -0,0 +1,153 @@
+package publisher
+
+import (
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+	"tyrattribution/config"
+
+	"github.com/IBM/sarama"
+)
+
+// asyncStatsInterval is how often the async producer logs its delivery counts.
+const asyncStatsInterval = time.Minute
+
+// asyncProducerStats holds the async producer's delivery totals since the
+// process started. They are published through expvar and served under
+// "kafka_async_producer" at /debug/vars.
+var asyncProducerStats = expvar.NewMap("kafka_async_producer")
+
+// KafkaAsyncProducer hands messages to a batching Kafka producer and returns
+// without waiting for the brokers. Delivery results arrive on the producer's
+// callback channels: successes are counted, and failed messages are counted
+// and written to the fallback producer, normally the outbox, so that they are
+// relayed later instead of lost.
+type KafkaAsyncProducer struct {
+	producer sarama.AsyncProducer
+	fallback Producer
+	done     chan struct{}
+
+	delivered       int64
+	failed          int64
+	fallbackWritten int64
+	lost            int64
+}
+
+func NewKafkaAsyncProducer(cfg *config.Config, fallback Producer) (*KafkaAsyncProducer, error) {
+	config, err := newKafkaProducerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	config.Producer.Return.Errors = true
+	config.Producer.Flush.Frequency = time.Duration(cfg.KafkaProducerLingerMillis) * time.Millisecond
+	config.Producer.Flush.Messages = cfg.KafkaProducerBatchSize
+
+	producer, err := sarama.NewAsyncProducer(cfg.KafkaBrokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka async producer: %w", err)
+	}
+
+	p := &KafkaAsyncProducer{
+		producer: producer,
+		fallback: fallback,
+		done:     make(chan struct{}),
+	}
+	go p.handleDeliveries()
+
+	return p, nil
+}
+
+// SendMessage queues the message and returns once the producer accepted it,
+// not when it is delivered.
+func (p *KafkaAsyncProducer) SendMessage(message Message) error {
+	producerMessage := toProducerMessage(message)
+	producerMessage.Metadata = message
+	p.producer.Input() <- producerMessage
+
+	return nil
+}
+
+func (p *KafkaAsyncProducer) SendMessages(messages []Message) []error {
+	errs := make([]error, len(messages))
+	for i, message := range messages {
+		errs[i] = p.SendMessage(message)
+	}
+
+	return errs
+}
+
+// Close flushes the queued messages and waits for their delivery results.
+func (p *KafkaAsyncProducer) Close() error {
+	p.producer.AsyncClose()
+	<-p.done
+
+	return nil
+}
+
+func (p *KafkaAsyncProducer) handleDeliveries() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(asyncStatsInterval)
+	defer ticker.Stop()
+
+	successes := p.producer.Successes()
+	errors := p.producer.Errors()
+
+	for successes != nil || errors != nil {
+		select {
+		case _, ok := <-successes:
+			if !ok {
+				successes = nil
+				continue
+			}
+			p.delivered++
+			asyncProducerStats.Add("delivered", 1)
+
+		case producerErr, ok := <-errors:
+			if !ok {
+				errors = nil
+				continue
+			}
+			p.failed++
+			asyncProducerStats.Add("failed", 1)
+			p.handleFailure(producerErr)
+
+		case <-ticker.C:
+			p.logStats()
+		}
+	}
+
+	p.logStats()
+}
+
+func (p *KafkaAsyncProducer) handleFailure(producerErr *sarama.ProducerError) {
+	message, ok := producerErr.Msg.Metadata.(Message)
+	if !ok {
+		p.lost++
+		asyncProducerStats.Add("lost", 1)
+		log.Printf("Failed to deliver message to %s and cannot recover it: %v", producerErr.Msg.Topic, producerErr.Err)
+		return
+	}
+
+	if err := p.fallback.SendMessage(message); err != nil {
+		p.lost++
+		asyncProducerStats.Add("lost", 1)
+		log.Printf("Failed to deliver message to %s and to write it to the fallback, message lost: %v (fallback: %v)", message.Topic, producerErr.Err, err)
+		return
+	}
+
+	p.fallbackWritten++
+	asyncProducerStats.Add("fallback_written", 1)
+	log.Printf("Failed to deliver message to %s, written to the fallback for relay: %v", message.Topic, producerErr.Err)
+}
+
+func (p *KafkaAsyncProducer) logStats() {
+	if p.delivered == 0 && p.failed == 0 {
+		return
+	}
+
+	log.Printf("Kafka async producer: %d delivered, %d failed, %d written to fallback, %d lost",
+		p.delivered, p.failed, p.fallbackWritten, p.lost)
+	p.delivered, p.failed, p.fallbackWritten, p.lost = 0, 0, 0, 0
+}
@@ -0,0 +1,62 @@
+package publisher
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryBus is an in-process event transport for single-binary deployments
+// and tests. Each topic is a buffered channel shared by its subscribers, which
+// compete for messages. Messages live only in memory and are lost on restart.
+type MemoryBus struct {
+	bufferSize int
+	mu         sync.Mutex
+	topics     map[string]chan Message
+}
+
+func NewMemoryBus(bufferSize int) *MemoryBus {
+	return &MemoryBus{
+		bufferSize: bufferSize,
+		topics:     make(map[string]chan Message),
+	}
+}
+
+// SendMessage never blocks; it fails when the topic's buffer is full so that
+// callers see back-pressure instead of hanging.
+func (b *MemoryBus) SendMessage(message Message) error {
+	select {
+	case b.Messages(message.Topic) <- message:
+		return nil
+	default:
+		return fmt.Errorf("memory bus topic %s is full", message.Topic)
+	}
+}
+
+func (b *MemoryBus) SendMessages(messages []Message) []error {
+	errs := make([]error, len(messages))
+	for i, message := range messages {
+		errs[i] = b.SendMessage(message)
+	}
+
+	return errs
+}
+
+// Messages returns the channel of the topic, creating it on first use.
+func (b *MemoryBus) Messages(topic string) chan Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	messages, ok := b.topics[topic]
+	if !ok {
+		messages = make(chan Message, b.bufferSize)
+		b.topics[topic] = messages
+	}
+
+	return messages
+}
+
+// Close is a no-op: the channels stay open so that late publishers get a full
+// buffer error instead of a panic.
+func (b *MemoryBus) Close() error {
+	return nil
+}
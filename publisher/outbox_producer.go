@@ -0,0 +1,54 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"tyrattribution/entity"
+	"tyrattribution/repository"
+)
+
+// OutboxProducer stores messages in the Postgres outbox instead of sending them
+// to Kafka. The OutboxRelay delivers them later, so publishing keeps working
+// while the brokers are down.
+type OutboxProducer struct {
+	outboxEventRepository repository.OutboxEventRepository
+}
+
+func NewOutboxProducer(outboxEventRepository repository.OutboxEventRepository) *OutboxProducer {
+	return &OutboxProducer{
+		outboxEventRepository: outboxEventRepository,
+	}
+}
+
+func (p *OutboxProducer) SendMessage(message Message) error {
+	return p.SendMessages([]Message{message})[0]
+}
+
+// SendMessages writes all messages in one insert, so they are stored or
+// rejected together.
+func (p *OutboxProducer) SendMessages(messages []Message) []error {
+	errs := make([]error, len(messages))
+	outboxEvents := make([]entity.OutboxEvent, len(messages))
+	now := time.Now()
+	for i, message := range messages {
+		outboxEvents[i] = entity.OutboxEvent{
+			Topic:      message.Topic,
+			MessageKey: message.Key,
+			Payload:    message.Value,
+			RetryAt:    now,
+		}
+	}
+
+	if err := p.outboxEventRepository.CreateBatch(context.Background(), outboxEvents); err != nil {
+		for i := range errs {
+			errs[i] = fmt.Errorf("failed to write outbox event: %w", err)
+		}
+	}
+
+	return errs
+}
+
+func (p *OutboxProducer) Close() error {
+	return nil
+}
@@ -0,0 +1,135 @@
+package publisher
+
+import (
+	"context"
+	"log"
+	"time"
+	"tyrattribution/config"
+	"tyrattribution/entity"
+	"tyrattribution/repository"
+)
+
+// outboxCleanupInterval is how often sent outbox events past their retention
+// are deleted.
+const outboxCleanupInterval = time.Hour
+
+// OutboxRelay publishes the events stored by the OutboxProducer to Kafka and
+// marks them sent, giving at-least-once delivery across broker outages. Several
+// instances can relay at once; each locks its own rows.
+type OutboxRelay struct {
+	outboxEventRepository repository.OutboxEventRepository
+	producer              Producer
+	batchSize             int
+	interval              time.Duration
+	retention             time.Duration
+	maxAttempts           int
+	retryBackoff          time.Duration
+	maxBackoff            time.Duration
+}
+
+func NewOutboxRelay(cfg *config.Config, outboxEventRepository repository.OutboxEventRepository, producer Producer) *OutboxRelay {
+	return &OutboxRelay{
+		outboxEventRepository: outboxEventRepository,
+		producer:              producer,
+		batchSize:             cfg.OutboxRelayBatchSize,
+		interval:              time.Duration(cfg.OutboxRelayIntervalMillis) * time.Millisecond,
+		retention:             time.Duration(cfg.OutboxRetentionHours) * time.Hour,
+		maxAttempts:           cfg.OutboxMaxAttempts,
+		retryBackoff:          time.Duration(cfg.OutboxRetryBackoffSeconds) * time.Second,
+		maxBackoff:            time.Duration(cfg.OutboxMaxBackoffSeconds) * time.Second,
+	}
+}
+
+// Start relays pending events until the context is cancelled. A batch that
+// sent anything is followed straight away by the next one, so a backlog drains
+// without waiting for the interval. A batch that sent nothing, as while Kafka
+// is down, waits for the interval. Events that fail are held back by their
+// retry delay, so they are not picked up again by the batches that follow.
+func (r *OutboxRelay) Start(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		sent, failed, err := r.outboxEventRepository.RelayPending(ctx, time.Now(), r.batchSize, r.maxAttempts, r.retryDelay, r.send)
+		if err != nil {
+			log.Printf("Failed to relay outbox events: %v", err)
+		} else if failed > 0 {
+			log.Printf("Marked %d outbox events failed after %d attempts", failed, r.maxAttempts)
+		}
+
+		if time.Since(lastCleanup) >= outboxCleanupInterval {
+			deleted, err := r.outboxEventRepository.DeleteSentBefore(ctx, time.Now().Add(-r.retention))
+			if err != nil {
+				log.Printf("Failed to delete sent outbox events: %v", err)
+			} else if deleted > 0 {
+				log.Printf("Deleted %d sent outbox events", deleted)
+			}
+			lastCleanup = time.Now()
+		}
+
+		if sent > 0 {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox relay context cancelled")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// retryDelay doubles the backoff after every failed attempt, up to the
+// configured maximum.
+func (r *OutboxRelay) retryDelay(attempts int) time.Duration {
+	delay := r.retryBackoff
+	for i := 1; i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > r.maxBackoff {
+		delay = r.maxBackoff
+	}
+
+	return delay
+}
+
+func (r *OutboxRelay) send(outboxEvents []entity.OutboxEvent) []error {
+	messages := make([]Message, len(outboxEvents))
+	for i, outboxEvent := range outboxEvents {
+		messages[i] = Message{
+			Topic: outboxEvent.Topic,
+			Key:   outboxEvent.MessageKey,
+			Value: outboxEvent.Payload,
+		}
+	}
+
+	errs := r.producer.SendMessages(messages)
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		log.Printf("Relayed %d of %d outbox events, %d will be retried", len(outboxEvents)-failed, len(outboxEvents), failed)
+	} else {
+		log.Printf("Relayed %d outbox events", len(outboxEvents))
+	}
+
+	return errs
+}
+
+func StartOutboxRelay(ctx context.Context, cfg *config.Config, outboxEventRepository repository.OutboxEventRepository, producer Producer) {
+	relay := NewOutboxRelay(cfg, outboxEventRepository, producer)
+
+	log.Println("Starting outbox relay")
+	relay.Start(ctx)
+}
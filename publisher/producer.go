@@ -0,0 +1,166 @@
+package publisher
+
+import (
+	"fmt"
+	"tyrattribution/config"
+	"tyrattribution/kafka"
+
+	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+)
+
+// Message is a serialized event ready to be delivered to a topic.
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Producer is the transport the event publishers deliver through. SendMessages
+// returns one entry per message, nil when that message was delivered.
+type Producer interface {
+	SendMessage(message Message) error
+	SendMessages(messages []Message) []error
+	Close() error
+}
+
+// KafkaProducer delivers messages straight to Kafka and only returns once the
+// brokers have acknowledged them.
+type KafkaProducer struct {
+	producer sarama.SyncProducer
+}
+
+func NewKafkaProducer(cfg *config.Config) (*KafkaProducer, error) {
+	config, err := newKafkaProducerConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	producer, err := sarama.NewSyncProducer(cfg.KafkaBrokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	return &KafkaProducer{
+		producer: producer,
+	}, nil
+}
+
+func (p *KafkaProducer) SendMessage(message Message) error {
+	if _, _, err := p.producer.SendMessage(toProducerMessage(message)); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return nil
+}
+
+func (p *KafkaProducer) SendMessages(messages []Message) []error {
+	errs := make([]error, len(messages))
+	if len(messages) == 0 {
+		return errs
+	}
+
+	producerMessages := make([]*sarama.ProducerMessage, len(messages))
+	indexes := make(map[*sarama.ProducerMessage]int, len(messages))
+	for i, message := range messages {
+		producerMessages[i] = toProducerMessage(message)
+		indexes[producerMessages[i]] = i
+	}
+
+	if err := p.producer.SendMessages(producerMessages); err != nil {
+		producerErrs, ok := err.(sarama.ProducerErrors)
+		if !ok {
+			for i := range errs {
+				errs[i] = fmt.Errorf("failed to send message: %w", err)
+			}
+			return errs
+		}
+
+		for _, producerErr := range producerErrs {
+			errs[indexes[producerErr.Msg]] = fmt.Errorf("failed to send message: %w", producerErr.Err)
+		}
+	}
+
+	return errs
+}
+
+func (p *KafkaProducer) Close() error {
+	return p.producer.Close()
+}
+
+// newKafkaProducerConfig builds the settings shared by the sync and async
+// producers: the connection settings, full acknowledgement, retries,
+// compression and, optionally, the idempotent producer.
+func newKafkaProducerConfig(cfg *config.Config) (*sarama.Config, error) {
+	config, err := kafka.NewConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	config.Producer.Return.Successes = true
+	config.Producer.RequiredAcks = sarama.WaitForAll
+	config.Producer.Retry.Max = 3
+
+	switch cfg.KafkaProducerCompression {
+	case "", "none":
+		config.Producer.Compression = sarama.CompressionNone
+	case "gzip":
+		config.Producer.Compression = sarama.CompressionGZIP
+	case "snappy":
+		config.Producer.Compression = sarama.CompressionSnappy
+	case "lz4":
+		config.Producer.Compression = sarama.CompressionLZ4
+	case "zstd":
+		config.Producer.Compression = sarama.CompressionZSTD
+		config.Version = sarama.V2_1_0_0
+	default:
+		return nil, fmt.Errorf("unknown Kafka compression %q, use none, gzip, snappy, lz4 or zstd", cfg.KafkaProducerCompression)
+	}
+
+	// The idempotent producer needs a single in-flight request per broker to
+	// keep ordering while retrying.
+	if cfg.KafkaProducerIdempotent {
+		config.Producer.Idempotent = true
+		config.Net.MaxOpenRequests = 1
+		if !config.Version.IsAtLeast(sarama.V0_11_0_0) {
+			config.Version = sarama.V0_11_0_0
+		}
+	}
+
+	return config, nil
+}
+
+// ValidatePartitionKey checks a KAFKA_PARTITION_KEY value.
+func ValidatePartitionKey(partitionKey string) error {
+	switch partitionKey {
+	case "user_id", "campaign_id", "event_id":
+		return nil
+	default:
+		return fmt.Errorf("unknown partition key %q, use user_id, campaign_id or event_id", partitionKey)
+	}
+}
+
+// messageKey picks the key, and so the partition, of an event. Keying by user
+// keeps each user's events in order within a topic and puts them on the
+// same partition number in every topic.
+func messageKey(partitionKey string, eventID, userID, campaignID uuid.UUID) []byte {
+	switch partitionKey {
+	case "campaign_id":
+		return []byte(campaignID.String())
+	case "event_id":
+		return []byte(eventID.String())
+	default:
+		return []byte(userID.String())
+	}
+}
+
+func toProducerMessage(message Message) *sarama.ProducerMessage {
+	producerMessage := &sarama.ProducerMessage{
+		Topic: message.Topic,
+		Value: sarama.ByteEncoder(message.Value),
+	}
+	if len(message.Key) > 0 {
+		producerMessage.Key = sarama.ByteEncoder(message.Key)
+	}
+
+	return producerMessage
+}
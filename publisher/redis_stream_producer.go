@@ -0,0 +1,74 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// RedisStreamProducer appends messages to Redis Streams, one stream per topic,
+// for deployments that do not run Kafka. Streams are trimmed to roughly maxLen
+// entries.
+type RedisStreamProducer struct {
+	client *goredis.Client
+	maxLen int64
+}
+
+func NewRedisStreamProducer(client *goredis.Client, maxLen int64) *RedisStreamProducer {
+	return &RedisStreamProducer{
+		client: client,
+		maxLen: maxLen,
+	}
+}
+
+func (p *RedisStreamProducer) SendMessage(message Message) error {
+	if err := p.client.XAdd(context.Background(), p.addArgs(message)).Err(); err != nil {
+		return fmt.Errorf("failed to add message to stream: %w", err)
+	}
+
+	return nil
+}
+
+// SendMessages appends all messages in a single pipeline.
+func (p *RedisStreamProducer) SendMessages(messages []Message) []error {
+	errs := make([]error, len(messages))
+	if len(messages) == 0 {
+		return errs
+	}
+
+	ctx := context.Background()
+	cmds := make([]*goredis.StringCmd, len(messages))
+	_, err := p.client.Pipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, message := range messages {
+			cmds[i] = pipe.XAdd(ctx, p.addArgs(message))
+		}
+		return nil
+	})
+
+	for i, cmd := range cmds {
+		if cmdErr := cmd.Err(); cmdErr != nil {
+			errs[i] = fmt.Errorf("failed to add message to stream: %w", cmdErr)
+		} else if err != nil && cmd.Val() == "" {
+			errs[i] = fmt.Errorf("failed to add message to stream: %w", err)
+		}
+	}
+
+	return errs
+}
+
+func (p *RedisStreamProducer) Close() error {
+	return p.client.Close()
+}
+
+func (p *RedisStreamProducer) addArgs(message Message) *goredis.XAddArgs {
+	return &goredis.XAddArgs{
+		Stream: message.Topic,
+		MaxLen: p.maxLen,
+		Approx: true,
+		Values: map[string]interface{}{
+			"key":   message.Key,
+			"value": message.Value,
+		},
+	}
+}
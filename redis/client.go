@@ -2,10 +2,43 @@ package redis
 
 import (
 	"context"
+	"time"
 )
 
 type Client interface {
-	Incr(ctx context.Context, key string) (int64, error)
-	Expire(ctx context.Context, key string, seconds int) error
+	Ping(ctx context.Context) error
 	Get(ctx context.Context, key string) (string, error)
-}
\ No newline at end of file
+	// MGet returns the values of the keys that exist, by key.
+	MGet(ctx context.Context, keys ...string) (map[string]string, error)
+	// IncrByExpireAt adds increment to the key and sets it to expire at
+	// expireAt in one script, so the key can never be left without an expiry.
+	// An expireAt in the past deletes the key.
+	IncrByExpireAt(ctx context.Context, key string, increment int64, expireAt time.Time) (int64, error)
+	IncrByFloat(ctx context.Context, key string, value float64) (float64, error)
+	HIncrBy(ctx context.Context, key string, field string, increment int64) (int64, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	PFAdd(ctx context.Context, key string, elements ...string) error
+	// PFCount returns the estimated number of distinct elements across the
+	// HyperLogLogs at the keys.
+	PFCount(ctx context.Context, keys ...string) (int64, error)
+	PFMerge(ctx context.Context, destination string, keys ...string) error
+	// Pipelined sends the commands fn queues as one MULTI/EXEC transaction in
+	// a single round trip, so no other client's command runs between them.
+	// Redis does not roll back: a command that fails leaves the others
+	// applied. IsReplyError tells such a failure from a connection error.
+	Pipelined(ctx context.Context, fn func(Pipeline) error) error
+}
+
+// Pipeline queues commands for Client.Pipelined. Replies are not returned;
+// Pipelined reports the first command that failed.
+type Pipeline interface {
+	IncrByExpireAt(ctx context.Context, key string, increment int64, expireAt time.Time)
+	IncrByFloat(ctx context.Context, key string, value float64)
+	HIncrBy(ctx context.Context, key string, field string, increment int64)
+	PFAdd(ctx context.Context, key string, elements ...string)
+	PFMerge(ctx context.Context, destination string, keys ...string)
+	// SetExpireAt overwrites the key with the value and sets it to expire at
+	// expireAt.
+	SetExpireAt(ctx context.Context, key string, value interface{}, expireAt time.Time)
+	ExpireAt(ctx context.Context, key string, expireAt time.Time)
+}
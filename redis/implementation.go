@@ -2,7 +2,9 @@ package redis
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"time"
 	"tyrattribution/config"
@@ -14,7 +16,33 @@ type ClientWrapper struct {
 	client *redis.Client
 }
 
+// NewClient returns a client even when Redis does not answer yet, since the
+// counters buffer their updates until it does. The client reconnects on its
+// own.
 func NewClient(cfg *config.Config) (Client, error) {
+	client := newRedisClient(cfg)
+
+	if err := ping(client); err != nil {
+		log.Printf("Redis unavailable at startup, counters will be buffered until it is reachable: %v", err)
+	}
+
+	return &ClientWrapper{client: client}, nil
+}
+
+// NewStreamClient opens a separate connection for the Redis Streams event
+// transport, which needs the stream commands the Client interface leaves out.
+// Unlike NewClient it fails when Redis does not answer.
+func NewStreamClient(cfg *config.Config) (*redis.Client, error) {
+	client := newRedisClient(cfg)
+
+	if err := ping(client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func newRedisClient(cfg *config.Config) *redis.Client {
 	redisURL := cfg.REDISURL
 	redisPassword := cfg.REDISPassword
 	redisDBStr := cfg.REDISDBStr
@@ -24,31 +52,142 @@ func NewClient(cfg *config.Config) (Client, error) {
 		redisDB = 0
 	}
 
-	client := redis.NewClient(&redis.Options{
+	return redis.NewClient(&redis.Options{
 		Addr:     redisURL,
 		Password: redisPassword,
 		DB:       redisDB,
 	})
+}
 
+func ping(client *redis.Client) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	_, err = client.Ping(ctx).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	if err := client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &ClientWrapper{client: client}, nil
+	return nil
 }
 
-func (r *ClientWrapper) Incr(ctx context.Context, key string) (int64, error) {
-	return r.client.Incr(ctx, key).Result()
+func (r *ClientWrapper) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
 }
 
-func (r *ClientWrapper) Expire(ctx context.Context, key string, seconds int) error {
-	return r.client.Expire(ctx, key, time.Duration(seconds)*time.Second).Err()
-}
+// incrByExpireAtScript adds ARGV[1] to KEYS[1] and sets it to expire at the
+// Unix time ARGV[2], returning the new value.
+var incrByExpireAtScript = redis.NewScript(`
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+redis.call("EXPIREAT", KEYS[1], ARGV[2])
+return value
+`)
 
 func (r *ClientWrapper) Get(ctx context.Context, key string) (string, error) {
 	return r.client.Get(ctx, key).Result()
 }
+
+func (r *ClientWrapper) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	results, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range results {
+		if value, ok := result.(string); ok {
+			values[keys[i]] = value
+		}
+	}
+
+	return values, nil
+}
+
+func (r *ClientWrapper) IncrByExpireAt(ctx context.Context, key string, increment int64, expireAt time.Time) (int64, error) {
+	return incrByExpireAtScript.Run(ctx, r.client, []string{key}, increment, expireAt.Unix()).Int64()
+}
+
+func (r *ClientWrapper) IncrByFloat(ctx context.Context, key string, value float64) (float64, error) {
+	return r.client.IncrByFloat(ctx, key, value).Result()
+}
+
+func (r *ClientWrapper) HIncrBy(ctx context.Context, key string, field string, increment int64) (int64, error) {
+	return r.client.HIncrBy(ctx, key, field, increment).Result()
+}
+
+func (r *ClientWrapper) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return r.client.HGetAll(ctx, key).Result()
+}
+
+func (r *ClientWrapper) PFAdd(ctx context.Context, key string, elements ...string) error {
+	return r.client.PFAdd(ctx, key, toArgs(elements)...).Err()
+}
+
+func (r *ClientWrapper) PFCount(ctx context.Context, keys ...string) (int64, error) {
+	return r.client.PFCount(ctx, keys...).Result()
+}
+
+func (r *ClientWrapper) PFMerge(ctx context.Context, destination string, keys ...string) error {
+	return r.client.PFMerge(ctx, destination, keys...).Err()
+}
+
+func (r *ClientWrapper) Pipelined(ctx context.Context, fn func(Pipeline) error) error {
+	// Scripts are sent with EVAL, since a NOSCRIPT reply to EVALSHA cannot be
+	// retried inside a transaction.
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		return fn(&pipelineWrapper{pipe: pipe})
+	})
+
+	return err
+}
+
+// IsReplyError reports whether err is an error reply from Redis, as opposed
+// to a connection failure. After a reply error inside Pipelined, Redis ran
+// the transaction and the other commands were applied.
+func IsReplyError(err error) bool {
+	var replyErr redis.Error
+	return errors.As(err, &replyErr) && !errors.Is(err, redis.Nil)
+}
+
+type pipelineWrapper struct {
+	pipe redis.Pipeliner
+}
+
+func (p *pipelineWrapper) IncrByExpireAt(ctx context.Context, key string, increment int64, expireAt time.Time) {
+	incrByExpireAtScript.Eval(ctx, p.pipe, []string{key}, increment, expireAt.Unix())
+}
+
+func (p *pipelineWrapper) IncrByFloat(ctx context.Context, key string, value float64) {
+	p.pipe.IncrByFloat(ctx, key, value)
+}
+
+func (p *pipelineWrapper) HIncrBy(ctx context.Context, key string, field string, increment int64) {
+	p.pipe.HIncrBy(ctx, key, field, increment)
+}
+
+func (p *pipelineWrapper) PFAdd(ctx context.Context, key string, elements ...string) {
+	p.pipe.PFAdd(ctx, key, toArgs(elements)...)
+}
+
+func (p *pipelineWrapper) PFMerge(ctx context.Context, destination string, keys ...string) {
+	p.pipe.PFMerge(ctx, destination, keys...)
+}
+
+func (p *pipelineWrapper) SetExpireAt(ctx context.Context, key string, value interface{}, expireAt time.Time) {
+	p.pipe.SetArgs(ctx, key, value, redis.SetArgs{ExpireAt: expireAt})
+}
+
+func (p *pipelineWrapper) ExpireAt(ctx context.Context, key string, expireAt time.Time) {
+	p.pipe.ExpireAt(ctx, key, expireAt)
+}
+
+func toArgs(elements []string) []interface{} {
+	args := make([]interface{}, len(elements))
+	for i, element := range elements {
+		args[i] = element
+	}
+	return args
+}
\ No newline at end of file
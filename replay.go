@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+	"tyrattribution/attribution"
+	"tyrattribution/config"
+	"tyrattribution/consumer"
+	"tyrattribution/counter"
+	"tyrattribution/database"
+	"tyrattribution/publisher"
+	"tyrattribution/redis"
+	"tyrattribution/repository"
+	"tyrattribution/service"
+
+	"github.com/google/uuid"
+)
+
+// replayedEvent holds the fields the replay needs from any event payload.
+type replayedEvent struct {
+	ConversionID   uuid.UUID `json:"conversion_id"`
+	ClickDate      time.Time `json:"click_date"`
+	ConversionDate time.Time `json:"conversion_date"`
+	ImpressionDate time.Time `json:"impression_date"`
+}
+
+func (e replayedEvent) date() time.Time {
+	switch {
+	case !e.ConversionDate.IsZero():
+		return e.ConversionDate
+	case !e.ClickDate.IsZero():
+		return e.ClickDate
+	default:
+		return e.ImpressionDate
+	}
+}
+
+// runReplay reprocesses a range of an event topic through the consumer
+// pipeline, for example after an attribution bug fix:
+//
+//	tyrattribution replay -topic click_conversion -from 2025-01-01T00:00:00Z -to 2025-01-08T00:00:00Z -model linear
+//
+// Events already stored are skipped by their event IDs. With -reattribute or
+// -model the replayed conversions are attributed again. Afterwards the
+// metrics of every day the replay touched are recounted from Postgres.
+func runReplay(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	topic := flags.String("topic", "", "event topic to replay (required)")
+	from := flags.String("from", "", "replay messages from this time, RFC 3339")
+	to := flags.String("to", "", "replay messages before this time, RFC 3339")
+	startOffset := flags.Int64("start-offset", -1, "first offset to replay on every partition")
+	endOffset := flags.Int64("end-offset", -1, "offset to stop before on every partition")
+	group := flags.String("group", "tyr-replay", "consumer group the replay commits its progress to")
+	resume := flags.Bool("resume", false, "continue an interrupted replay from the group's committed offsets")
+	reattribute := flags.Bool("reattribute", false, "clear and redo the attribution of replayed conversions")
+	modelName := flags.String("model", "", "re-attribute replayed conversions with this model for every campaign")
+	rebuild := flags.Bool("rebuild", true, "recount Redis counters and journal rows of the replayed days")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *topic == "" {
+		return errors.New("-topic is required")
+	}
+	if cfg.EventTransport != "kafka" {
+		return fmt.Errorf("replay needs the kafka transport, EVENT_TRANSPORT is %s", cfg.EventTransport)
+	}
+	if (*from != "" && *startOffset >= 0) || (*to != "" && *endOffset >= 0) {
+		return errors.New("use either a time range or an offset range, not both")
+	}
+	if *modelName != "" {
+		*reattribute = true
+	}
+	if *reattribute && *topic != cfg.KafkaConversionTopic {
+		return fmt.Errorf("-reattribute and -model only apply to the conversion topic %s", cfg.KafkaConversionTopic)
+	}
+
+	options := consumer.ReplayOptions{
+		Topic:       *topic,
+		Group:       *group,
+		Resume:      *resume,
+		StartOffset: *startOffset,
+		EndOffset:   *endOffset,
+		BatchSize:   cfg.ConsumerBatchSize,
+	}
+
+	var err error
+	if *from != "" {
+		if options.From, err = time.Parse(time.RFC3339, *from); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+	if *to != "" {
+		if options.To, err = time.Parse(time.RFC3339, *to); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+	}
+
+	db, err := database.OpenDatabase(cfg)
+	if err != nil {
+		return err
+	}
+
+	redisClient, err := redis.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	counterStore := counter.NewRedisStore(redisClient)
+
+	clickEventRepo := repository.NewClickEventRepository(db)
+	impressionEventRepo := repository.NewImpressionEventRepository(db)
+	conversionEventRepo := repository.NewConversionEventRepository(db)
+	campaignRepo := repository.NewCampaignRepository(db)
+	campaignJournalRepo := repository.NewCampaignJournalRepository(db)
+	campaignSettingRepo := repository.NewCampaignSettingRepository(db)
+	pendingAttributionRepo := repository.NewPendingAttributionRepository(db)
+
+	attributionModel, err := attribution.NewModel(cfg.AttributionModel, cfg.AttributionHalfLifeHours)
+	if err != nil {
+		return err
+	}
+
+	campaignSettingService := service.NewCampaignSettingService(campaignSettingRepo, attributionModel, cfg)
+	if *modelName != "" {
+		overrideModel, err := attribution.NewModel(*modelName, cfg.AttributionHalfLifeHours)
+		if err != nil {
+			return err
+		}
+		campaignSettingService = service.NewModelOverrideSettingService(campaignSettingService, overrideModel)
+	}
+
+	clickEventService := service.NewClickEventService(clickEventRepo, pendingAttributionRepo, counterStore)
+	impressionEventService := service.NewImpressionEventService(impressionEventRepo, counterStore)
+	conversionEventService := service.NewConversionEventService(conversionEventRepo, pendingAttributionRepo, campaignJournalRepo, clickEventService, impressionEventService, campaignSettingService, counterStore, cfg)
+	campaignJournalService := service.NewCampaignJournalService(campaignJournalRepo, campaignRepo, clickEventRepo, conversionEventRepo, counterStore)
+
+	producer, err := publisher.NewKafkaProducer(cfg)
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+	failedMessagePublisher := publisher.NewFailedMessagePublisher(cfg, producer)
+
+	pipeline, err := consumer.ReplayHandler(cfg, *topic, clickEventService, conversionEventService, impressionEventService, failedMessagePublisher)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	touchedDates := make(map[string]time.Time)
+
+	handler := func(ctx context.Context, deliveries []consumer.Delivery) error {
+		conversionIDs := make([]uuid.UUID, 0, len(deliveries))
+
+		mu.Lock()
+		for _, delivery := range deliveries {
+			var event replayedEvent
+			if err := json.Unmarshal(delivery.Value, &event); err != nil {
+				continue
+			}
+
+			if date := event.date(); !date.IsZero() {
+				touchedDates[date.Format("2006-01-02")] = date
+			}
+			if event.ConversionID != uuid.Nil {
+				conversionIDs = append(conversionIDs, event.ConversionID)
+			}
+		}
+		mu.Unlock()
+
+		if *reattribute {
+			if err := conversionEventService.ResetAttribution(ctx, conversionIDs); err != nil {
+				return err
+			}
+		}
+
+		return pipeline(ctx, deliveries)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Replaying %s into group %s", *topic, *group)
+	replayed, replayErr := consumer.NewKafkaReplayer(cfg).Replay(ctx, options, handler)
+	log.Printf("Replayed %d message(s) from %s", replayed, *topic)
+
+	if *rebuild && ctx.Err() == nil {
+		dates := make([]string, 0, len(touchedDates))
+		for dateStr := range touchedDates {
+			dates = append(dates, dateStr)
+		}
+		sort.Strings(dates)
+
+		for _, dateStr := range dates {
+			if err := campaignJournalService.RebuildMetrics(ctx, touchedDates[dateStr]); err != nil {
+				log.Printf("Failed to rebuild metrics for %s: %v", dateStr, err)
+			}
+		}
+	}
+
+	if replayErr != nil {
+		return fmt.Errorf("replay stopped early, run it again with -resume and the same group to continue: %w", replayErr)
+	}
+
+	return nil
+}
@@ -5,11 +5,32 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"tyrattribution/entity"
 )
 
+// DailyEventCounts is a campaign's totals for one day, counted from the event
+// tables. Conversions are counted when attributed and not duplicates.
+type DailyEventCounts struct {
+	CampaignID      uuid.UUID
+	Impressions     int64
+	Clicks          int64
+	Conversions     int64
+	ConversionValue decimal.Decimal
+	// ClickUsers and ConversionUsers are exact distinct user counts. Only
+	// CountCampaignDailyEvents fills them in.
+	ClickUsers      int64
+	ConversionUsers int64
+}
+
 type CampaignJournalRepository interface {
 	Create(ctx context.Context, campaignJournal *entity.CampaignJournal) error
 	GetByCampaignAndDate(ctx context.Context, campaignID uuid.UUID, date time.Time) (*entity.CampaignJournal, error)
+	GetByDate(ctx context.Context, date time.Time) ([]entity.CampaignJournal, error)
 	Update(ctx context.Context, campaignJournal *entity.CampaignJournal) error
+	// AddConversion adds one conversion and its value to the campaign's journal
+	// row for the date and reports whether such a row exists.
+	AddConversion(ctx context.Context, campaignID uuid.UUID, date time.Time, value decimal.Decimal) (bool, error)
+	CountDailyEvents(ctx context.Context, date time.Time) ([]DailyEventCounts, error)
+	CountCampaignDailyEvents(ctx context.Context, campaignID uuid.UUID, date time.Time) (*DailyEventCounts, error)
 }
\ No newline at end of file
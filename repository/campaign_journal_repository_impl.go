@@ -2,11 +2,13 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"tyrattribution/entity"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -40,6 +42,104 @@ func (r *campaignJournalRepository) GetByCampaignAndDate(ctx context.Context, ca
 	return &campaignJournal, nil
 }
 
+func (r *campaignJournalRepository) GetByDate(ctx context.Context, date time.Time) ([]entity.CampaignJournal, error) {
+	var campaignJournals []entity.CampaignJournal
+
+	dateOnly := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	err := r.db.WithContext(ctx).
+		Where("date = ?", dateOnly).
+		Find(&campaignJournals).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return campaignJournals, nil
+}
+
 func (r *campaignJournalRepository) Update(ctx context.Context, campaignJournal *entity.CampaignJournal) error {
 	return r.db.WithContext(ctx).Save(campaignJournal).Error
 }
+
+func (r *campaignJournalRepository) AddConversion(ctx context.Context, campaignID uuid.UUID, date time.Time, value decimal.Decimal) (bool, error) {
+	dateOnly := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+
+	result := r.db.WithContext(ctx).
+		Model(&entity.CampaignJournal{}).
+		Where("campaign_id = ? AND date = ?", campaignID, dateOnly).
+		Updates(map[string]interface{}{
+			"number_of_conversion":   gorm.Expr("COALESCE(number_of_conversion, 0) + 1"),
+			"total_conversion_value": gorm.Expr("COALESCE(total_conversion_value, 0) + ?", value),
+		})
+
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *campaignJournalRepository) CountDailyEvents(ctx context.Context, date time.Time) ([]DailyEventCounts, error) {
+	var counts []DailyEventCounts
+	dateStr := date.Format("2006-01-02")
+
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT campaign_id,
+			SUM(impressions) AS impressions,
+			SUM(clicks) AS clicks,
+			SUM(conversions) AS conversions,
+			SUM(conversion_value) AS conversion_value
+		FROM (
+			SELECT campaign_id, COUNT(*) AS impressions, 0 AS clicks, 0 AS conversions, 0 AS conversion_value
+			FROM impression_event
+			WHERE DATE(impression_date) = ?
+			GROUP BY campaign_id
+			UNION ALL
+			SELECT campaign_id, 0, COUNT(*), 0, 0
+			FROM click_event
+			WHERE DATE(click_date) = ?
+			GROUP BY campaign_id
+			UNION ALL
+			SELECT campaign_id, 0, 0, COUNT(*), COALESCE(SUM(value), 0)
+			FROM conversion_event
+			WHERE DATE(conversion_date) = ? AND (click_id IS NOT NULL OR impression_id IS NOT NULL) AND is_duplicate = false
+			GROUP BY campaign_id
+		) daily
+		GROUP BY campaign_id`, dateStr, dateStr, dateStr).
+		Scan(&counts).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+func (r *campaignJournalRepository) CountCampaignDailyEvents(ctx context.Context, campaignID uuid.UUID, date time.Time) (*DailyEventCounts, error) {
+	counts := DailyEventCounts{CampaignID: campaignID}
+	dateStr := date.Format("2006-01-02")
+
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT
+			(SELECT COUNT(*)
+				FROM impression_event
+				WHERE campaign_id = @campaign AND DATE(impression_date) = @date) AS impressions,
+			clicks.clicks,
+			clicks.click_users,
+			conversions.conversions,
+			conversions.conversion_value,
+			conversions.conversion_users
+		FROM (
+			SELECT COUNT(*) AS clicks, COUNT(DISTINCT user_id) AS click_users
+			FROM click_event
+			WHERE campaign_id = @campaign AND DATE(click_date) = @date
+		) clicks, (
+			SELECT COUNT(*) AS conversions, COALESCE(SUM(value), 0) AS conversion_value, COUNT(DISTINCT user_id) AS conversion_users
+			FROM conversion_event
+			WHERE campaign_id = @campaign AND DATE(conversion_date) = @date AND (click_id IS NOT NULL OR impression_id IS NOT NULL) AND is_duplicate = false
+		) conversions`, sql.Named("campaign", campaignID), sql.Named("date", dateStr)).
+		Scan(&counts).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &counts, nil
+}
\ No newline at end of file
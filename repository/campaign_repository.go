@@ -11,4 +11,8 @@ type CampaignRepository interface {
 	Create(ctx context.Context, campaign *entity.Campaign) error
 	GetByID(ctx context.Context, campaignID uuid.UUID) (*entity.Campaign, error)
 	GetDistinctCampaignIDsFromClickEvents(ctx context.Context, date string) ([]uuid.UUID, error)
+	GetDistinctCampaignIDsFromImpressionEvents(ctx context.Context, date string) ([]uuid.UUID, error)
+	// GetDistinctCampaignIDsFromConversionEvents returns the campaigns with
+	// attributed, non-duplicate conversions on the date.
+	GetDistinctCampaignIDsFromConversionEvents(ctx context.Context, date string) ([]uuid.UUID, error)
 }
\ No newline at end of file
@@ -46,4 +46,28 @@ func (r *campaignRepository) GetDistinctCampaignIDsFromClickEvents(ctx context.C
 		Pluck("campaign_id", &campaignIDs).Error
 
 	return campaignIDs, err
-}
\ No newline at end of file
+}
+
+func (r *campaignRepository) GetDistinctCampaignIDsFromImpressionEvents(ctx context.Context, date string) ([]uuid.UUID, error) {
+	var campaignIDs []uuid.UUID
+
+	err := r.db.WithContext(ctx).
+		Model(&entity.ImpressionEvent{}).
+		Select("DISTINCT campaign_id").
+		Where("DATE(impression_date) = ?", date).
+		Pluck("campaign_id", &campaignIDs).Error
+
+	return campaignIDs, err
+}
+
+func (r *campaignRepository) GetDistinctCampaignIDsFromConversionEvents(ctx context.Context, date string) ([]uuid.UUID, error) {
+	var campaignIDs []uuid.UUID
+
+	err := r.db.WithContext(ctx).
+		Model(&entity.ConversionEvent{}).
+		Select("DISTINCT campaign_id").
+		Where("DATE(conversion_date) = ? AND (click_id IS NOT NULL OR impression_id IS NOT NULL) AND is_duplicate = false", date).
+		Pluck("campaign_id", &campaignIDs).Error
+
+	return campaignIDs, err
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"tyrattribution/entity"
+
+	"github.com/google/uuid"
+)
+
+type CampaignSettingRepository interface {
+	GetByCampaignID(ctx context.Context, campaignID uuid.UUID) (*entity.CampaignSetting, error)
+	Upsert(ctx context.Context, campaignSetting *entity.CampaignSetting) error
+	// GetMaxWindowHours returns the widest look-back and view-through windows
+	// any campaign overrides, or zero when none does.
+	GetMaxWindowHours(ctx context.Context) (int, int, error)
+}
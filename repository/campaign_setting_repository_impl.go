@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+
+	"tyrattribution/entity"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type campaignSettingRepository struct {
+	db *gorm.DB
+}
+
+func NewCampaignSettingRepository(db *gorm.DB) CampaignSettingRepository {
+	return &campaignSettingRepository{
+		db: db,
+	}
+}
+
+func (r *campaignSettingRepository) GetByCampaignID(ctx context.Context, campaignID uuid.UUID) (*entity.CampaignSetting, error) {
+	var campaignSetting entity.CampaignSetting
+
+	err := r.db.WithContext(ctx).
+		Where("campaign_id = ?", campaignID).
+		First(&campaignSetting).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &campaignSetting, nil
+}
+
+func (r *campaignSettingRepository) Upsert(ctx context.Context, campaignSetting *entity.CampaignSetting) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "campaign_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"lookback_window_hours",
+				"attribution_model",
+				"time_decay_half_life_hours",
+				"counted_conversion_types",
+				"view_through_window_hours",
+				"updated_at",
+			}),
+		}).
+		Create(campaignSetting).Error
+}
+
+func (r *campaignSettingRepository) GetMaxWindowHours(ctx context.Context) (int, int, error) {
+	var result struct {
+		LookbackWindowHours    int
+		ViewThroughWindowHours int
+	}
+
+	err := r.db.WithContext(ctx).
+		Model(&entity.CampaignSetting{}).
+		Select("COALESCE(MAX(lookback_window_hours), 0) AS lookback_window_hours, COALESCE(MAX(view_through_window_hours), 0) AS view_through_window_hours").
+		Scan(&result).Error
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return result.LookbackWindowHours, result.ViewThroughWindowHours, nil
+}
@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
@@ -10,9 +9,30 @@ import (
 
 type CampaignStatisticsData struct {
 	Period           string          `json:"period"`
+	TotalImpressions int64           `json:"total_impressions"`
 	TotalClicks      int64           `json:"total_clicks"`
 	TotalConversions int64           `json:"total_conversions"`
 	TotalValue       decimal.Decimal `json:"total_value"`
+	// UniqueClickUsers and UniqueConversionUsers are only filled in for daily
+	// data. Weekly and monthly ones are merged from the days' sketches.
+	UniqueClickUsers      int64 `json:"unique_click_users"`
+	UniqueConversionUsers int64 `json:"unique_conversion_users"`
+}
+
+// PeriodUserSketches holds the unique user sketches of one journal day, with
+// the weekly or monthly period the day belongs to.
+type PeriodUserSketches struct {
+	Period                string
+	ClickUsersSketch      []byte
+	ConversionUsersSketch []byte
+}
+
+// PeriodAttributedConversions holds the fractional conversions and credited
+// value a campaign received in one period from the attribution_credit table.
+type PeriodAttributedConversions struct {
+	Period                string          `json:"period"`
+	FractionalConversions decimal.Decimal `json:"fractional_conversions"`
+	CreditedValue         decimal.Decimal `json:"credited_value"`
 }
 
 type GroupBy string
@@ -25,5 +45,8 @@ const (
 
 type CampaignStatisticsRepository interface {
 	GetHistoricalData(ctx context.Context, campaignID uuid.UUID, groupBy GroupBy) ([]CampaignStatisticsData, error)
-	GetTodayConversionValue(ctx context.Context, campaignID uuid.UUID, date time.Time) (decimal.Decimal, error)
-}
\ No newline at end of file
+	GetUserSketches(ctx context.Context, campaignID uuid.UUID, groupBy GroupBy) ([]PeriodUserSketches, error)
+	// GetAttributedConversions sums the campaign's attribution credits by the
+	// daily, weekly or monthly period of their conversion, today included.
+	GetAttributedConversions(ctx context.Context, campaignID uuid.UUID, groupBy GroupBy) ([]PeriodAttributedConversions, error)
+}
@@ -33,9 +33,12 @@ func (r *CampaignStatisticsRepositoryImpl) GetHistoricalData(ctx context.Context
 			Model(&entity.CampaignJournal{}).
 			Select(`
 				date as period,
+				COALESCE(number_of_impression, 0) as total_impressions,
 				COALESCE(number_of_click, 0) as total_clicks,
 				COALESCE(number_of_conversion, 0) as total_conversions,
-				COALESCE(total_conversion_value, 0) as total_value
+				COALESCE(total_conversion_value, 0) as total_value,
+				COALESCE(unique_click_users, 0) as unique_click_users,
+				COALESCE(unique_conversion_users, 0) as unique_conversion_users
 			`).
 			Where("campaign_id = ? AND date < ?", campaignID, time.Now().Format("2006-01-02")).
 			Order("date DESC").
@@ -46,6 +49,7 @@ func (r *CampaignStatisticsRepositoryImpl) GetHistoricalData(ctx context.Context
 			Model(&entity.CampaignJournal{}).
 			Select(`
 				TO_CHAR(DATE_TRUNC('week', date), 'YYYY-MM-DD') as period,
+				SUM(COALESCE(number_of_impression, 0)) as total_impressions,
 				SUM(COALESCE(number_of_click, 0)) as total_clicks,
 				SUM(COALESCE(number_of_conversion, 0)) as total_conversions,
 				SUM(COALESCE(total_conversion_value, 0)) as total_value
@@ -60,6 +64,7 @@ func (r *CampaignStatisticsRepositoryImpl) GetHistoricalData(ctx context.Context
 			Model(&entity.CampaignJournal{}).
 			Select(`
 				TO_CHAR(DATE_TRUNC('month', date), 'YYYY-MM') as period,
+				SUM(COALESCE(number_of_impression, 0)) as total_impressions,
 				SUM(COALESCE(number_of_click, 0)) as total_clicks,
 				SUM(COALESCE(number_of_conversion, 0)) as total_conversions,
 				SUM(COALESCE(total_conversion_value, 0)) as total_value
@@ -73,9 +78,13 @@ func (r *CampaignStatisticsRepositoryImpl) GetHistoricalData(ctx context.Context
 
 	type QueryResult struct {
 		Period           *string         `json:"period"`
+		TotalImpressions int64           `json:"total_impressions"`
 		TotalClicks      int64           `json:"total_clicks"`
 		TotalConversions int64           `json:"total_conversions"`
 		TotalValue       decimal.Decimal `json:"total_value"`
+		// Unique users are only selected for daily data.
+		UniqueClickUsers      int64 `json:"unique_click_users"`
+		UniqueConversionUsers int64 `json:"unique_conversion_users"`
 	}
 
 	var queryResults []QueryResult
@@ -94,30 +103,80 @@ func (r *CampaignStatisticsRepositoryImpl) GetHistoricalData(ctx context.Context
 		periodStr = *result.Period
 
 		results = append(results, CampaignStatisticsData{
-			Period:           periodStr,
-			TotalClicks:      result.TotalClicks,
-			TotalConversions: result.TotalConversions,
-			TotalValue:       result.TotalValue,
+			Period:                periodStr,
+			TotalImpressions:      result.TotalImpressions,
+			TotalClicks:           result.TotalClicks,
+			TotalConversions:      result.TotalConversions,
+			TotalValue:            result.TotalValue,
+			UniqueClickUsers:      result.UniqueClickUsers,
+			UniqueConversionUsers: result.UniqueConversionUsers,
 		})
 	}
 
 	return results, nil
 }
 
-func (r *CampaignStatisticsRepositoryImpl) GetTodayConversionValue(ctx context.Context, campaignID uuid.UUID, date time.Time) (decimal.Decimal, error) {
-	var totalValue decimal.Decimal
-	dateStr := date.Format("2006-01-02")
+// GetUserSketches returns the unique user sketches of the campaign's journal
+// days before today, labelled with their weekly or monthly period.
+func (r *CampaignStatisticsRepositoryImpl) GetUserSketches(ctx context.Context, campaignID uuid.UUID, groupBy GroupBy) ([]PeriodUserSketches, error) {
+	var period string
+	switch groupBy {
+	case GroupByWeekly:
+		period = "TO_CHAR(DATE_TRUNC('week', date), 'YYYY-MM-DD')"
+	case GroupByMonthly:
+		period = "TO_CHAR(DATE_TRUNC('month', date), 'YYYY-MM')"
+	default:
+		period = "TO_CHAR(date, 'YYYY-MM-DD')"
+	}
 
+	var sketches []PeriodUserSketches
 	err := r.db.WithContext(ctx).
-		Model(&entity.ConversionEvent{}).
-		Select("COALESCE(SUM(value), 0)").
-		Where("campaign_id = ? AND DATE(conversion_date) = ? AND click_id IS NOT NULL", campaignID, dateStr).
-		Scan(&totalValue).Error
-
+		Model(&entity.CampaignJournal{}).
+		Select(period+" as period, click_users_sketch, conversion_users_sketch").
+		Where("campaign_id = ? AND date < ? AND (click_users_sketch IS NOT NULL OR conversion_users_sketch IS NOT NULL)", campaignID, time.Now().Format("2006-01-02")).
+		Scan(&sketches).Error
 	if err != nil {
-		log.Printf("Failed to get conversion value for date %s: %v", dateStr, err)
-		return decimal.Zero, err
+		return nil, err
+	}
+
+	return sketches, nil
+}
+
+func (r *CampaignStatisticsRepositoryImpl) GetAttributedConversions(ctx context.Context, campaignID uuid.UUID, groupBy GroupBy) ([]PeriodAttributedConversions, error) {
+	now := time.Now()
+
+	var period string
+	var since time.Time
+	switch groupBy {
+	case GroupByWeekly:
+		period = "TO_CHAR(DATE_TRUNC('week', conversion_event.conversion_date), 'YYYY-MM-DD')"
+	case GroupByMonthly:
+		period = "TO_CHAR(DATE_TRUNC('month', conversion_event.conversion_date), 'YYYY-MM')"
+		since = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -12, 0)
+	default:
+		period = "TO_CHAR(conversion_event.conversion_date, 'YYYY-MM-DD')"
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -30)
+	}
+
+	query := r.db.WithContext(ctx).
+		Model(&entity.AttributionCredit{}).
+		Select(period+` as period,
+			COALESCE(SUM(attribution_credit.weight), 0) as fractional_conversions,
+			COALESCE(SUM(attribution_credit.credited_value), 0) as credited_value
+		`).
+		Joins("JOIN conversion_event ON conversion_event.conversion_id = attribution_credit.conversion_id").
+		Where("attribution_credit.campaign_id = ?", campaignID).
+		Group(period)
+
+	if !since.IsZero() {
+		query = query.Where("conversion_event.conversion_date >= ?", since)
+	}
+
+	var data []PeriodAttributedConversions
+	if err := query.Scan(&data).Error; err != nil {
+		log.Printf("Failed to get attributed conversions for campaign %s: %v", campaignID.String(), err)
+		return nil, err
 	}
 
-	return totalValue, nil
+	return data, nil
 }
@@ -14,9 +14,27 @@ type ClickEventRepository interface {
 		campaignID uuid.UUID,
 		userID uuid.UUID,
 		source string,
-		clickDate time.Time,
+		conversionDate time.Time,
 		timeWindowHours int,
-	) (*entity.ClickEvent, error)
+		minDelaySeconds int,
+	) ([]entity.ClickEvent, error)
 
-	Create(ctx context.Context, clickEvent *entity.ClickEvent) error
-}
\ No newline at end of file
+	GetClickEventsByUserWithinTimeWindow(
+		ctx context.Context,
+		userID uuid.UUID,
+		conversionDate time.Time,
+		timeWindowHours int,
+		minDelaySeconds int,
+	) ([]entity.ClickEvent, error)
+
+	GetByID(ctx context.Context, clickID uuid.UUID) (*entity.ClickEvent, error)
+
+	// Create inserts the event unless one with the same ID already exists and
+	// reports whether a new row was written.
+	Create(ctx context.Context, clickEvent *entity.ClickEvent) (bool, error)
+
+	// CreateBatch bulk inserts the events that are not stored yet and returns
+	// the ones it wrote, leaving out events a concurrent writer inserted
+	// first. Events repeated within the batch are inserted once.
+	CreateBatch(ctx context.Context, clickEvents []*entity.ClickEvent) ([]*entity.ClickEvent, error)
+}
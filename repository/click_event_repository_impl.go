@@ -6,9 +6,13 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"tyrattribution/entity"
 )
 
+// bulkInsertBatchSize caps the rows per INSERT statement in bulk writes.
+const bulkInsertBatchSize = 500
+
 type clickEventRepository struct {
 	db *gorm.DB
 }
@@ -24,18 +28,55 @@ func (r *clickEventRepository) GetClickEventsByCampaignUserSourceWithinTimeWindo
 	campaignID uuid.UUID,
 	userID uuid.UUID,
 	source string,
-	clickDate time.Time,
+	conversionDate time.Time,
 	timeWindowHours int,
-) (*entity.ClickEvent, error) {
-	var clickEvent entity.ClickEvent
+	minDelaySeconds int,
+) ([]entity.ClickEvent, error) {
+	var clickEvents []entity.ClickEvent
 
-	startTime := clickDate.Add(-time.Duration(timeWindowHours) * time.Hour)
-	endTime := clickDate.Add(time.Duration(timeWindowHours) * time.Hour)
+	startTime, endTime := lookbackWindow(conversionDate, timeWindowHours, minDelaySeconds)
 
 	err := r.db.WithContext(ctx).
 		Where("campaign_id = ? AND user_id = ? AND source = ? AND click_date BETWEEN ? AND ?",
 			campaignID, userID, source, startTime, endTime).
-		Order("click_date DESC").
+		Order("click_date ASC").
+		Find(&clickEvents).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clickEvents, nil
+}
+
+func (r *clickEventRepository) GetClickEventsByUserWithinTimeWindow(
+	ctx context.Context,
+	userID uuid.UUID,
+	conversionDate time.Time,
+	timeWindowHours int,
+	minDelaySeconds int,
+) ([]entity.ClickEvent, error) {
+	var clickEvents []entity.ClickEvent
+
+	startTime, endTime := lookbackWindow(conversionDate, timeWindowHours, minDelaySeconds)
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND click_date BETWEEN ? AND ?", userID, startTime, endTime).
+		Order("click_date ASC").
+		Find(&clickEvents).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return clickEvents, nil
+}
+
+func (r *clickEventRepository) GetByID(ctx context.Context, clickID uuid.UUID) (*entity.ClickEvent, error) {
+	var clickEvent entity.ClickEvent
+
+	err := r.db.WithContext(ctx).
+		Where("click_id = ?", clickID).
 		First(&clickEvent).Error
 
 	if err != nil {
@@ -45,6 +86,95 @@ func (r *clickEventRepository) GetClickEventsByCampaignUserSourceWithinTimeWindo
 	return &clickEvent, nil
 }
 
-func (r *clickEventRepository) Create(ctx context.Context, clickEvent *entity.ClickEvent) error {
-	return r.db.WithContext(ctx).Create(clickEvent).Error
-}
\ No newline at end of file
+func (r *clickEventRepository) Create(ctx context.Context, clickEvent *entity.ClickEvent) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(clickEvent)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *clickEventRepository) CreateBatch(ctx context.Context, clickEvents []*entity.ClickEvent) ([]*entity.ClickEvent, error) {
+	if len(clickEvents) == 0 {
+		return nil, nil
+	}
+
+	var newEvents []*entity.ClickEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seen := make(map[uuid.UUID]bool, len(clickEvents))
+		unique := make([]*entity.ClickEvent, 0, len(clickEvents))
+		for _, clickEvent := range clickEvents {
+			if seen[clickEvent.ClickID] {
+				continue
+			}
+			seen[clickEvent.ClickID] = true
+			unique = append(unique, clickEvent)
+		}
+
+		for start := 0; start < len(unique); start += bulkInsertBatchSize {
+			batch := unique[start:min(start+bulkInsertBatchSize, len(unique))]
+
+			insertedIDs, err := insertReturningIDs(tx, batch, "click_id")
+			if err != nil {
+				return err
+			}
+
+			for _, clickEvent := range batch {
+				if insertedIDs[clickEvent.ClickID] {
+					newEvents = append(newEvents, clickEvent)
+				}
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newEvents, nil
+}
+
+// insertReturningIDs inserts the rows, skipping those whose key is already
+// stored, and returns the idColumn values of the rows it actually wrote. Rows
+// inserted by a concurrent writer are therefore left out, which a lookup
+// before the insert would miss.
+func insertReturningIDs(tx *gorm.DB, rows interface{}, idColumn string) (map[uuid.UUID]bool, error) {
+	stmt := tx.Session(&gorm.Session{DryRun: true}).
+		Clauses(clause.OnConflict{DoNothing: true}, clause.Returning{Columns: []clause.Column{{Name: idColumn}}}).
+		Create(rows).Statement
+	if stmt.Error != nil {
+		return nil, stmt.Error
+	}
+
+	result, err := tx.Statement.ConnPool.QueryContext(tx.Statement.Context, stmt.SQL.String(), stmt.Vars...)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	insertedIDs := make(map[uuid.UUID]bool)
+	for result.Next() {
+		var id uuid.UUID
+		if err := result.Scan(&id); err != nil {
+			return nil, err
+		}
+		insertedIDs[id] = true
+	}
+
+	return insertedIDs, result.Err()
+}
+
+// lookbackWindow returns the range of click dates that may be credited with a
+// conversion: clicks inside the window before the conversion, and at least
+// minDelaySeconds older than it.
+func lookbackWindow(conversionDate time.Time, timeWindowHours int, minDelaySeconds int) (time.Time, time.Time) {
+	startTime := conversionDate.Add(-time.Duration(timeWindowHours) * time.Hour)
+	endTime := conversionDate.Add(-time.Duration(minDelaySeconds) * time.Second)
+	return startTime, endTime
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 	"tyrattribution/entity"
 
 	"github.com/google/uuid"
@@ -9,7 +10,27 @@ import (
 )
 
 type ConversionEventRepository interface {
-	Create(ctx context.Context, conversionEvent *entity.ConversionEvent) error
+	// Create inserts the event unless one with the same ID already exists and
+	// reports whether a new row was written.
+	Create(ctx context.Context, conversionEvent *entity.ConversionEvent) (bool, error)
+	// CreateBatch bulk inserts the events that are not stored yet and returns
+	// the ones it wrote, leaving out events a concurrent writer inserted
+	// first. Events repeated within the batch are inserted once.
+	CreateBatch(ctx context.Context, conversionEvents []*entity.ConversionEvent) ([]*entity.ConversionEvent, error)
 	Update(ctx context.Context, conversionEvent *entity.ConversionEvent) error
+	// Attribute saves the conversion's attribution together with its
+	// attribution credits in one transaction, so a conversion is never stored
+	// as attributed without its credits. A conversion with an order ID is
+	// marked duplicate, and saved without credits, when another counted
+	// conversion in its campaign carries the order within dedupWindow of its
+	// date. A zero dedupWindow leaves the range open. The order is locked for
+	// the transaction, so concurrent conversions of one order are checked one
+	// after the other.
+	Attribute(ctx context.Context, conversionEvent *entity.ConversionEvent, credits []entity.AttributionCredit, dedupWindow time.Duration) error
+	GetByID(ctx context.Context, conversionID uuid.UUID) (*entity.ConversionEvent, error)
+	// ResetAttribution clears the attribution and duplicate flag of the
+	// conversions and deletes their attribution credits, so they are
+	// attributed again when next processed.
+	ResetAttribution(ctx context.Context, conversionIDs []uuid.UUID) error
 	GetTotalConversionValue(ctx context.Context, campaignID uuid.UUID, date string) (decimal.Decimal, error)
 }
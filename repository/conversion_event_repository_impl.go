@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"tyrattribution/entity"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type conversionEventRepository struct {
@@ -20,21 +22,156 @@ func NewConversionEventRepository(db *gorm.DB) ConversionEventRepository {
 	}
 }
 
-func (r *conversionEventRepository) Create(ctx context.Context, conversionEvent *entity.ConversionEvent) error {
-	return r.db.WithContext(ctx).Create(conversionEvent).Error
+func (r *conversionEventRepository) Create(ctx context.Context, conversionEvent *entity.ConversionEvent) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(conversionEvent)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
+
+func (r *conversionEventRepository) CreateBatch(ctx context.Context, conversionEvents []*entity.ConversionEvent) ([]*entity.ConversionEvent, error) {
+	if len(conversionEvents) == 0 {
+		return nil, nil
+	}
+
+	var newEvents []*entity.ConversionEvent
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		seen := make(map[uuid.UUID]bool, len(conversionEvents))
+		unique := make([]*entity.ConversionEvent, 0, len(conversionEvents))
+		for _, conversionEvent := range conversionEvents {
+			if seen[conversionEvent.ConversionID] {
+				continue
+			}
+			seen[conversionEvent.ConversionID] = true
+			unique = append(unique, conversionEvent)
+		}
+
+		for start := 0; start < len(unique); start += bulkInsertBatchSize {
+			batch := unique[start:min(start+bulkInsertBatchSize, len(unique))]
+
+			insertedIDs, err := insertReturningIDs(tx, batch, "conversion_id")
+			if err != nil {
+				return err
+			}
+
+			for _, conversionEvent := range batch {
+				if insertedIDs[conversionEvent.ConversionID] {
+					newEvents = append(newEvents, conversionEvent)
+				}
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newEvents, nil
 }
 
 func (r *conversionEventRepository) Update(ctx context.Context, conversionEvent *entity.ConversionEvent) error {
 	return r.db.WithContext(ctx).Save(conversionEvent).Error
 }
 
+func (r *conversionEventRepository) Attribute(ctx context.Context, conversionEvent *entity.ConversionEvent, credits []entity.AttributionCredit, dedupWindow time.Duration) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		conversionEvent.IsDuplicate = false
+
+		if conversionEvent.OrderID != nil {
+			orderKey := conversionEvent.CampaignID.String() + ":" + *conversionEvent.OrderID
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtextextended(?, 0))", orderKey).Error; err != nil {
+				return err
+			}
+
+			duplicate, err := existsCountedOrder(tx, conversionEvent, dedupWindow)
+			if err != nil {
+				return err
+			}
+			conversionEvent.IsDuplicate = duplicate
+		}
+
+		if err := tx.Save(conversionEvent).Error; err != nil {
+			return err
+		}
+
+		if conversionEvent.IsDuplicate || len(credits) == 0 {
+			return nil
+		}
+
+		return tx.Create(&credits).Error
+	})
+}
+
+func (r *conversionEventRepository) GetByID(ctx context.Context, conversionID uuid.UUID) (*entity.ConversionEvent, error) {
+	var conversionEvent entity.ConversionEvent
+
+	err := r.db.WithContext(ctx).
+		Where("conversion_id = ?", conversionID).
+		First(&conversionEvent).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &conversionEvent, nil
+}
+
+func (r *conversionEventRepository) ResetAttribution(ctx context.Context, conversionIDs []uuid.UUID) error {
+	if len(conversionIDs) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("conversion_id IN ?", conversionIDs).Delete(&entity.AttributionCredit{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&entity.ConversionEvent{}).
+			Where("conversion_id IN ?", conversionIDs).
+			Updates(map[string]interface{}{
+				"click_id":      nil,
+				"impression_id": nil,
+				"is_duplicate":  false,
+			}).Error
+	})
+}
+
+// existsCountedOrder reports whether another attributed, non-duplicate
+// conversion in the campaign carries the conversion's order ID within
+// dedupWindow on either side of its date.
+func existsCountedOrder(tx *gorm.DB, conversionEvent *entity.ConversionEvent, dedupWindow time.Duration) (bool, error) {
+	var count int64
+
+	query := tx.Model(&entity.ConversionEvent{}).
+		Where("campaign_id = ? AND order_id = ? AND conversion_id <> ?", conversionEvent.CampaignID, *conversionEvent.OrderID, conversionEvent.ConversionID).
+		Where("is_duplicate = false AND (click_id IS NOT NULL OR impression_id IS NOT NULL)")
+
+	if dedupWindow > 0 {
+		query = query.Where("conversion_date BETWEEN ? AND ?",
+			conversionEvent.ConversionDate.Add(-dedupWindow), conversionEvent.ConversionDate.Add(dedupWindow))
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 func (s *conversionEventRepository) GetTotalConversionValue(ctx context.Context, campaignID uuid.UUID, date string) (decimal.Decimal, error) {
 	var totalValue decimal.Decimal
 
 	err := s.db.WithContext(ctx).
 		Model(&entity.ConversionEvent{}).
 		Select("COALESCE(SUM(value), 0)").
-		Where("campaign_id = ? AND DATE(conversion_date) = ? AND click_id IS NOT NULL", campaignID, date).
+		Where("campaign_id = ? AND DATE(conversion_date) = ? AND (click_id IS NOT NULL OR impression_id IS NOT NULL) AND is_duplicate = false", campaignID, date).
 		Scan(&totalValue).Error
 
 	if err != nil {
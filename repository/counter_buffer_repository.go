@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"tyrattribution/entity"
+)
+
+type CounterBufferRepository interface {
+	CreateBatch(ctx context.Context, counterBuffers []entity.CounterBuffer) error
+	// FlushPending locks up to limit buffered updates, oldest first, and hands
+	// them to flush. They are deleted in the same transaction when flush
+	// succeeds. Updates locked by another instance are skipped. It returns how
+	// many updates were flushed.
+	FlushPending(ctx context.Context, limit int, flush func(counterBuffers []entity.CounterBuffer) error) (int, error)
+	// Exists reports whether any update is buffered, including updates locked
+	// by another instance.
+	Exists(ctx context.Context) (bool, error)
+	Count(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+
+	"tyrattribution/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type counterBufferRepository struct {
+	db *gorm.DB
+}
+
+func NewCounterBufferRepository(db *gorm.DB) CounterBufferRepository {
+	return &counterBufferRepository{
+		db: db,
+	}
+}
+
+func (r *counterBufferRepository) CreateBatch(ctx context.Context, counterBuffers []entity.CounterBuffer) error {
+	if len(counterBuffers) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).CreateInBatches(&counterBuffers, bulkInsertBatchSize).Error
+}
+
+func (r *counterBufferRepository) FlushPending(ctx context.Context, limit int, flush func(counterBuffers []entity.CounterBuffer) error) (int, error) {
+	var flushed int
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var counterBuffers []entity.CounterBuffer
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Order("counter_buffer_id ASC").
+			Limit(limit).
+			Find(&counterBuffers).Error
+		if err != nil {
+			return err
+		}
+
+		if len(counterBuffers) == 0 {
+			return nil
+		}
+
+		if err := flush(counterBuffers); err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(counterBuffers))
+		for i, counterBuffer := range counterBuffers {
+			ids[i] = counterBuffer.CounterBufferID
+		}
+
+		if err := tx.Where("counter_buffer_id IN ?", ids).Delete(&entity.CounterBuffer{}).Error; err != nil {
+			return err
+		}
+
+		flushed = len(counterBuffers)
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return flushed, nil
+}
+
+func (r *counterBufferRepository) Exists(ctx context.Context) (bool, error) {
+	var exists bool
+	err := r.db.WithContext(ctx).
+		Raw("SELECT EXISTS (SELECT 1 FROM counter_buffer)").
+		Scan(&exists).Error
+	return exists, err
+}
+
+func (r *counterBufferRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.CounterBuffer{}).Count(&count).Error
+	return count, err
+}
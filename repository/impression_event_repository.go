@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"tyrattribution/entity"
+)
+
+type ImpressionEventRepository interface {
+	GetImpressionEventsByCampaignUserSourceWithinTimeWindow(
+		ctx context.Context,
+		campaignID uuid.UUID,
+		userID uuid.UUID,
+		source string,
+		conversionDate time.Time,
+		timeWindowHours int,
+		minDelaySeconds int,
+	) ([]entity.ImpressionEvent, error)
+
+	GetImpressionEventsByUserWithinTimeWindow(
+		ctx context.Context,
+		userID uuid.UUID,
+		conversionDate time.Time,
+		timeWindowHours int,
+		minDelaySeconds int,
+	) ([]entity.ImpressionEvent, error)
+
+	// Create inserts the event unless one with the same ID already exists and
+	// reports whether a new row was written.
+	Create(ctx context.Context, impressionEvent *entity.ImpressionEvent) (bool, error)
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"tyrattribution/entity"
+)
+
+type impressionEventRepository struct {
+	db *gorm.DB
+}
+
+func NewImpressionEventRepository(db *gorm.DB) ImpressionEventRepository {
+	return &impressionEventRepository{
+		db: db,
+	}
+}
+
+func (r *impressionEventRepository) GetImpressionEventsByCampaignUserSourceWithinTimeWindow(
+	ctx context.Context,
+	campaignID uuid.UUID,
+	userID uuid.UUID,
+	source string,
+	conversionDate time.Time,
+	timeWindowHours int,
+	minDelaySeconds int,
+) ([]entity.ImpressionEvent, error) {
+	var impressionEvents []entity.ImpressionEvent
+
+	startTime, endTime := lookbackWindow(conversionDate, timeWindowHours, minDelaySeconds)
+
+	err := r.db.WithContext(ctx).
+		Where("campaign_id = ? AND user_id = ? AND source = ? AND impression_date BETWEEN ? AND ?",
+			campaignID, userID, source, startTime, endTime).
+		Order("impression_date ASC").
+		Find(&impressionEvents).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return impressionEvents, nil
+}
+
+func (r *impressionEventRepository) GetImpressionEventsByUserWithinTimeWindow(
+	ctx context.Context,
+	userID uuid.UUID,
+	conversionDate time.Time,
+	timeWindowHours int,
+	minDelaySeconds int,
+) ([]entity.ImpressionEvent, error) {
+	var impressionEvents []entity.ImpressionEvent
+
+	startTime, endTime := lookbackWindow(conversionDate, timeWindowHours, minDelaySeconds)
+
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND impression_date BETWEEN ? AND ?", userID, startTime, endTime).
+		Order("impression_date ASC").
+		Find(&impressionEvents).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return impressionEvents, nil
+}
+
+func (r *impressionEventRepository) Create(ctx context.Context, impressionEvent *entity.ImpressionEvent) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(impressionEvent)
+
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tyrattribution/entity"
+)
+
+type OutboxEventRepository interface {
+	CreateBatch(ctx context.Context, outboxEvents []entity.OutboxEvent) error
+	// RelayPending locks up to limit unsent events whose retry time has passed,
+	// oldest first, and hands them to send. Events send reports as delivered
+	// are marked sent. The others get their attempt count and last error
+	// updated and are retried after retryDelay of their new attempt count, or
+	// are marked failed once they reach maxAttempts (0 for no limit). All of
+	// it happens in the same transaction. Events locked by another relay are
+	// skipped. It returns how many events were sent and how many were marked
+	// failed.
+	RelayPending(ctx context.Context, now time.Time, limit int, maxAttempts int, retryDelay func(attempts int) time.Duration, send func(outboxEvents []entity.OutboxEvent) []error) (int, int, error)
+	DeleteSentBefore(ctx context.Context, before time.Time) (int64, error)
+}
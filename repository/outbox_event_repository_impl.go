@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tyrattribution/entity"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type outboxEventRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxEventRepository(db *gorm.DB) OutboxEventRepository {
+	return &outboxEventRepository{
+		db: db,
+	}
+}
+
+func (r *outboxEventRepository) CreateBatch(ctx context.Context, outboxEvents []entity.OutboxEvent) error {
+	if len(outboxEvents) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).CreateInBatches(&outboxEvents, bulkInsertBatchSize).Error
+}
+
+func (r *outboxEventRepository) RelayPending(ctx context.Context, now time.Time, limit int, maxAttempts int, retryDelay func(attempts int) time.Duration, send func(outboxEvents []entity.OutboxEvent) []error) (int, int, error) {
+	var sent, failed int
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		sent, failed = 0, 0
+
+		var outboxEvents []entity.OutboxEvent
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("sent_at IS NULL AND failed_at IS NULL AND retry_at <= ?", now).
+			Order("outbox_event_id ASC").
+			Limit(limit).
+			Find(&outboxEvents).Error
+		if err != nil {
+			return err
+		}
+
+		if len(outboxEvents) == 0 {
+			return nil
+		}
+
+		errs := send(outboxEvents)
+		sentIDs := make([]int64, 0, len(outboxEvents))
+
+		for i, outboxEvent := range outboxEvents {
+			if errs[i] == nil {
+				sentIDs = append(sentIDs, outboxEvent.OutboxEventID)
+				continue
+			}
+
+			attempts := outboxEvent.Attempts + 1
+			updates := map[string]interface{}{
+				"attempts":   attempts,
+				"last_error": errs[i].Error(),
+				"retry_at":   now.Add(retryDelay(attempts)),
+			}
+			if maxAttempts > 0 && attempts >= maxAttempts {
+				updates["failed_at"] = now
+				failed++
+			}
+
+			err := tx.Model(&entity.OutboxEvent{}).
+				Where("outbox_event_id = ?", outboxEvent.OutboxEventID).
+				Updates(updates).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(sentIDs) == 0 {
+			return nil
+		}
+
+		err = tx.Model(&entity.OutboxEvent{}).
+			Where("outbox_event_id IN ?", sentIDs).
+			Update("sent_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		sent = len(sentIDs)
+		return nil
+	})
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return sent, failed, nil
+}
+
+func (r *outboxEventRepository) DeleteSentBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("sent_at IS NOT NULL AND sent_at < ?", before).
+		Delete(&entity.OutboxEvent{})
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tyrattribution/entity"
+
+	"github.com/google/uuid"
+)
+
+type PendingAttributionRepository interface {
+	// Create queues the conversion unless it is already queued.
+	Create(ctx context.Context, pendingAttribution *entity.PendingAttribution) error
+	// ProcessDue locks up to limit queued conversions whose retry time has
+	// passed, oldest first, and hands them to process. Conversions process
+	// reports as done are removed, unless process moved their RetryAt past
+	// now to keep them queued. Failed ones get their last error recorded and
+	// are retried after retryDelay. Kept and failed rows have their attempt
+	// count raised, all in the same transaction. Rows locked by another worker
+	// are skipped. It returns how many rows were processed without error.
+	ProcessDue(ctx context.Context, now time.Time, limit int, retryDelay time.Duration, process func(pendingAttributions []entity.PendingAttribution) []error) (int, error)
+	// RescheduleUsers makes the queued conversions of the given users due at
+	// retryAt, when they were due later.
+	RescheduleUsers(ctx context.Context, userIDs []uuid.UUID, retryAt time.Time) (int64, error)
+}
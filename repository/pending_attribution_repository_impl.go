@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tyrattribution/entity"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type pendingAttributionRepository struct {
+	db *gorm.DB
+}
+
+func NewPendingAttributionRepository(db *gorm.DB) PendingAttributionRepository {
+	return &pendingAttributionRepository{
+		db: db,
+	}
+}
+
+func (r *pendingAttributionRepository) Create(ctx context.Context, pendingAttribution *entity.PendingAttribution) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(pendingAttribution).Error
+}
+
+func (r *pendingAttributionRepository) ProcessDue(ctx context.Context, now time.Time, limit int, retryDelay time.Duration, process func(pendingAttributions []entity.PendingAttribution) []error) (int, error) {
+	var processed int
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		processed = 0
+
+		var pendingAttributions []entity.PendingAttribution
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("retry_at <= ?", now).
+			Order("retry_at ASC").
+			Limit(limit).
+			Find(&pendingAttributions).Error
+		if err != nil {
+			return err
+		}
+
+		if len(pendingAttributions) == 0 {
+			return nil
+		}
+
+		errs := process(pendingAttributions)
+		doneIDs := make([]uuid.UUID, 0, len(pendingAttributions))
+
+		for i, pendingAttribution := range pendingAttributions {
+			if errs[i] == nil {
+				processed++
+			}
+
+			if errs[i] == nil && !pendingAttribution.RetryAt.After(now) {
+				doneIDs = append(doneIDs, pendingAttribution.ConversionID)
+				continue
+			}
+
+			if errs[i] == nil {
+				err := tx.Model(&entity.PendingAttribution{}).
+					Where("conversion_id = ?", pendingAttribution.ConversionID).
+					Updates(map[string]interface{}{
+						"attempts":   gorm.Expr("attempts + 1"),
+						"last_error": nil,
+						"retry_at":   pendingAttribution.RetryAt,
+					}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			lastError := errs[i].Error()
+			err := tx.Model(&entity.PendingAttribution{}).
+				Where("conversion_id = ?", pendingAttribution.ConversionID).
+				Updates(map[string]interface{}{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": lastError,
+					"retry_at":   now.Add(retryDelay),
+				}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(doneIDs) == 0 {
+			return nil
+		}
+
+		return tx.Where("conversion_id IN ?", doneIDs).
+			Delete(&entity.PendingAttribution{}).Error
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return processed, nil
+}
+
+func (r *pendingAttributionRepository) RescheduleUsers(ctx context.Context, userIDs []uuid.UUID, retryAt time.Time) (int64, error) {
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.WithContext(ctx).
+		Model(&entity.PendingAttribution{}).
+		Where("user_id IN ? AND retry_at > ?", userIDs, retryAt).
+		Update("retry_at", retryAt)
+
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"tyrattribution/entity"
+)
+
+type TrackedLinkRepository interface {
+	// Create returns gorm.ErrDuplicatedKey when the link ID is already taken.
+	Create(ctx context.Context, trackedLink *entity.TrackedLink) error
+	GetByID(ctx context.Context, linkID string) (*entity.TrackedLink, error)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"tyrattribution/entity"
+)
+
+type trackedLinkRepository struct {
+	db *gorm.DB
+}
+
+func NewTrackedLinkRepository(db *gorm.DB) TrackedLinkRepository {
+	return &trackedLinkRepository{
+		db: db,
+	}
+}
+
+// uniqueViolationCode is the Postgres error code for a unique constraint
+// violation.
+const uniqueViolationCode = "23505"
+
+func (r *trackedLinkRepository) Create(ctx context.Context, trackedLink *entity.TrackedLink) error {
+	err := r.db.WithContext(ctx).Create(trackedLink).Error
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolationCode {
+		return gorm.ErrDuplicatedKey
+	}
+
+	return err
+}
+
+func (r *trackedLinkRepository) GetByID(ctx context.Context, linkID string) (*entity.TrackedLink, error) {
+	var trackedLink entity.TrackedLink
+
+	err := r.db.WithContext(ctx).
+		Where("link_id = ?", linkID).
+		First(&trackedLink).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &trackedLink, nil
+}
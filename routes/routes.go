@@ -1,24 +1,47 @@
 package routes
 
 import (
+	"expvar"
 	"net/http"
+	"tyrattribution/config"
+	"tyrattribution/consumer"
+	"tyrattribution/counter"
 	"tyrattribution/handler"
 	"tyrattribution/publisher"
 	"tyrattribution/service"
 )
 
-func SetupRoutes(clickEventPublisher *publisher.ClickEventPublisher, conversionEventPublisher *publisher.ConversionEventPublisher, campaignJournalService service.CampaignJournalService, campaignStatisticsService service.CampaignStatisticsService) *http.ServeMux {
+func SetupRoutes(clickEventPublisher publisher.ClickEventPublisher, conversionEventPublisher publisher.ConversionEventPublisher, impressionEventPublisher publisher.ImpressionEventPublisher, campaignJournalService service.CampaignJournalService, reconciliationService service.ReconciliationService, campaignStatisticsService service.CampaignStatisticsService, campaignSettingService service.CampaignSettingService, trackedLinkService service.TrackedLinkService, deadLetterRedriver *consumer.DeadLetterRedriver, counterStore *counter.ResilientStore, cfg *config.Config) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	clickEventHandler := handler.NewClickEventHandler(clickEventPublisher)
-	conversionEventHandler := handler.NewConversionEventHandler(conversionEventPublisher)
+	conversionEventHandler := handler.NewConversionEventHandler(conversionEventPublisher, cfg.AttributionCrossCampaign)
+	impressionEventHandler := handler.NewImpressionEventHandler(impressionEventPublisher)
+	eventBatchHandler := handler.NewEventBatchHandler(clickEventPublisher, conversionEventPublisher, cfg.EventBatchMaxEvents, cfg.AttributionCrossCampaign)
+	trackedLinkHandler := handler.NewTrackedLinkHandler(trackedLinkService, campaignSettingService, clickEventPublisher, cfg)
 	campaignJournalHandler := handler.NewCampaignJournalHandler(campaignJournalService)
 	campaignStatisticsHandler := handler.NewCampaignStatisticsHandler(campaignStatisticsService)
+	campaignSettingHandler := handler.NewCampaignSettingHandler(campaignSettingService)
+	deadLetterHandler := handler.NewDeadLetterHandler(deadLetterRedriver)
+	reconciliationHandler := handler.NewReconciliationHandler(reconciliationService)
+	counterHealthHandler := handler.NewCounterHealthHandler(counterStore)
 
 	mux.HandleFunc("POST /api/clicks", clickEventHandler.CreateClickEvent)
 	mux.HandleFunc("POST /api/conversions", conversionEventHandler.CreateConversionEvent)
+	mux.HandleFunc("GET /px/conversion", conversionEventHandler.TrackConversionPixel)
+	mux.HandleFunc("GET /postback", conversionEventHandler.HandlePostback)
+	mux.HandleFunc("POST /api/impressions", impressionEventHandler.CreateImpressionEvent)
+	mux.HandleFunc("POST /api/events/batch", eventBatchHandler.CreateEventBatch)
+	mux.HandleFunc("POST /api/tracked-links", trackedLinkHandler.CreateTrackedLink)
+	mux.HandleFunc("GET /r/{link_id}", trackedLinkHandler.Redirect)
 	mux.HandleFunc("POST /api/calculate-yesterday-metrics", campaignJournalHandler.CalculateYesterdayMetrics)
 	mux.HandleFunc("GET /api/campaign-statistics", campaignStatisticsHandler.GetCampaignStatistics)
+	mux.HandleFunc("GET /api/campaign-settings", campaignSettingHandler.GetCampaignSetting)
+	mux.HandleFunc("PUT /api/campaign-settings", campaignSettingHandler.UpdateCampaignSetting)
+	mux.HandleFunc("POST /api/admin/dead-letters/redrive", deadLetterHandler.RedriveDeadLetters)
+	mux.HandleFunc("POST /api/admin/reconcile", reconciliationHandler.Reconcile)
+	mux.HandleFunc("GET /api/admin/counters/health", counterHealthHandler.GetCounterHealth)
+	mux.Handle("GET /debug/vars", expvar.Handler())
 
 	return mux
 }
@@ -2,8 +2,12 @@ package service
 
 import (
 	"context"
+	"time"
+	"tyrattribution/repository"
 )
 
 type CampaignJournalService interface {
 	CalculateYesterdayMetrics(ctx context.Context) error
+	RebuildMetrics(ctx context.Context, date time.Time) error
+	SaveDailyCounts(ctx context.Context, date time.Time, counts repository.DailyEventCounts) error
 }
\ No newline at end of file
@@ -4,11 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strconv"
 	"time"
 
+	"tyrattribution/counter"
 	"tyrattribution/entity"
-	"tyrattribution/redis"
 	"tyrattribution/repository"
 
 	"github.com/google/uuid"
@@ -16,12 +15,21 @@ import (
 	"gorm.io/gorm"
 )
 
+// dailyUniqueUsers holds a day's unique user estimates and the serialized
+// sketches behind them.
+type dailyUniqueUsers struct {
+	clickUsers       int64
+	conversionUsers  int64
+	clickSketch      []byte
+	conversionSketch []byte
+}
+
 type CampaignJournalServiceImpl struct {
 	campaignJournalRepo repository.CampaignJournalRepository
 	campaignRepo        repository.CampaignRepository
 	clickEventRepo      repository.ClickEventRepository
 	conversionEventRepo repository.ConversionEventRepository
-	redisClient         redis.Client
+	counterStore        counter.Store
 }
 
 func NewCampaignJournalService(
@@ -29,14 +37,14 @@ func NewCampaignJournalService(
 	campaignRepo repository.CampaignRepository,
 	clickEventRepo repository.ClickEventRepository,
 	conversionEventRepo repository.ConversionEventRepository,
-	redisClient redis.Client,
+	counterStore counter.Store,
 ) CampaignJournalService {
 	return &CampaignJournalServiceImpl{
 		campaignJournalRepo: campaignJournalRepo,
 		campaignRepo:        campaignRepo,
 		clickEventRepo:      clickEventRepo,
 		conversionEventRepo: conversionEventRepo,
-		redisClient:         redisClient,
+		counterStore:        counterStore,
 	}
 }
 
@@ -51,10 +59,26 @@ func (s *CampaignJournalServiceImpl) CalculateYesterdayMetrics(ctx context.Conte
 		return fmt.Errorf("failed to get campaign IDs from click events: %w", err)
 	}
 
-	log.Printf("Found %d campaigns with click events on %s", len(campaignIDs), dateStr)
+	impressionCampaignIDs, err := s.campaignRepo.GetDistinctCampaignIDsFromImpressionEvents(ctx, dateStr)
+	if err != nil {
+		return fmt.Errorf("failed to get campaign IDs from impression events: %w", err)
+	}
+
+	// A conversion can be attributed to a campaign through a click or
+	// impression from an earlier day, so campaigns without events of their
+	// own that day still get a row for their conversions.
+	conversionCampaignIDs, err := s.campaignRepo.GetDistinctCampaignIDsFromConversionEvents(ctx, dateStr)
+	if err != nil {
+		return fmt.Errorf("failed to get campaign IDs from conversion events: %w", err)
+	}
+
+	campaignIDs = mergeCampaignIDs(campaignIDs, impressionCampaignIDs)
+	campaignIDs = mergeCampaignIDs(campaignIDs, conversionCampaignIDs)
+
+	log.Printf("Found %d campaigns with click, impression or attributed conversion events on %s", len(campaignIDs), dateStr)
 
 	for _, campaignID := range campaignIDs {
-		if err := s.processCampaignMetrics(ctx, campaignID, yesterday, dateStr); err != nil {
+		if err := s.processCampaignMetrics(ctx, campaignID, yesterday); err != nil {
 			log.Printf("Failed to process metrics for campaign %s: %v", campaignID.String(), err)
 			continue
 		}
@@ -63,39 +87,135 @@ func (s *CampaignJournalServiceImpl) CalculateYesterdayMetrics(ctx context.Conte
 	return nil
 }
 
-func (s *CampaignJournalServiceImpl) processCampaignMetrics(ctx context.Context, campaignID uuid.UUID, date time.Time, dateStr string) error {
+func (s *CampaignJournalServiceImpl) processCampaignMetrics(ctx context.Context, campaignID uuid.UUID, date time.Time) error {
 	if err := s.ensureCampaignExists(ctx, campaignID); err != nil {
 		return fmt.Errorf("failed to ensure campaign exists: %w", err)
 	}
 
-	clickCount, err := s.getClickCountFromRedis(ctx, campaignID, dateStr)
+	counts, err := s.counterStore.GetDay(ctx, campaignID, date)
 	if err != nil {
-		log.Printf("Failed to get click count from Redis for campaign %s: %v", campaignID.String(), err)
-		clickCount = 0
+		log.Printf("Failed to get counters from Redis for campaign %s, counting from Postgres: %v", campaignID.String(), err)
+		return s.processCampaignMetricsFromEvents(ctx, campaignID, date)
 	}
 
-	conversionCount, err := s.getConversionCountFromRedis(ctx, campaignID, dateStr)
+	totalConversionValue := counter.FromCents(counts.ConversionValueCents)
+
+	uniqueUsers := &dailyUniqueUsers{
+		clickUsers:      counts.UniqueClickUsers,
+		conversionUsers: counts.UniqueConversionUsers,
+	}
+	if uniqueUsers.clickSketch, err = s.counterStore.Sketch(ctx, counter.NewKey(counter.ClickUsers, campaignID, date)); err != nil {
+		log.Printf("Failed to get click users sketch from Redis for campaign %s: %v", campaignID.String(), err)
+	}
+	if uniqueUsers.conversionSketch, err = s.counterStore.Sketch(ctx, counter.NewKey(counter.ConversionUsers, campaignID, date)); err != nil {
+		log.Printf("Failed to get conversion users sketch from Redis for campaign %s: %v", campaignID.String(), err)
+	}
+
+	if err := s.saveJournal(ctx, campaignID, date, counts.Impressions, counts.Clicks, counts.Conversions, totalConversionValue, uniqueUsers); err != nil {
+		return err
+	}
+
+	log.Printf("Campaign %s metrics - Impressions: %d, Clicks: %d, Conversions: %d, Total Value: %s, Unique Click Users: %d, Unique Conversion Users: %d",
+		campaignID.String(), counts.Impressions, counts.Clicks, counts.Conversions, totalConversionValue.String(), uniqueUsers.clickUsers, uniqueUsers.conversionUsers)
+
+	return nil
+}
+
+// processCampaignMetricsFromEvents writes the journal row from counts taken
+// from the event tables, for when the Redis counters cannot be read. The
+// unique users are exact counts, and no sketches are stored, so the day is
+// left out of merged weekly and monthly unique users.
+func (s *CampaignJournalServiceImpl) processCampaignMetricsFromEvents(ctx context.Context, campaignID uuid.UUID, date time.Time) error {
+	counts, err := s.campaignJournalRepo.CountCampaignDailyEvents(ctx, campaignID, date)
 	if err != nil {
-		log.Printf("Failed to get conversion count from Redis for campaign %s: %v", campaignID.String(), err)
-		conversionCount = 0
+		return fmt.Errorf("failed to count events for campaign %s: %w", campaignID.String(), err)
+	}
+
+	uniqueUsers := &dailyUniqueUsers{
+		clickUsers:      counts.ClickUsers,
+		conversionUsers: counts.ConversionUsers,
 	}
 
-	totalConversionValue, err := s.getTotalConversionValueFromDB(ctx, campaignID, dateStr)
+	if err := s.saveJournal(ctx, campaignID, date, counts.Impressions, counts.Clicks, counts.Conversions, counts.ConversionValue, uniqueUsers); err != nil {
+		return err
+	}
+
+	log.Printf("Campaign %s metrics from Postgres - Impressions: %d, Clicks: %d, Conversions: %d, Total Value: %s",
+		campaignID.String(), counts.Impressions, counts.Clicks, counts.Conversions, counts.ConversionValue.String())
+
+	return nil
+}
+
+// RebuildMetrics recounts a day's metrics from the event tables, for use after
+// a replay. The day's Redis counters are overwritten while they are live, and
+// journal rows are written for days before today. Unique user estimates cannot
+// be recounted from a total, so journal rows keep the ones they have.
+func (s *CampaignJournalServiceImpl) RebuildMetrics(ctx context.Context, date time.Time) error {
+	dateStr := date.Format("2006-01-02")
+
+	counts, err := s.campaignJournalRepo.CountDailyEvents(ctx, date)
 	if err != nil {
-		log.Printf("Failed to get total conversion value for campaign %s: %v", campaignID.String(), err)
-		totalConversionValue = decimal.Zero
+		return fmt.Errorf("failed to count events for %s: %w", dateStr, err)
+	}
+
+	now := time.Now()
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if counter.IsLive(date, now) {
+		counters := make(map[uuid.UUID]counter.Counts, len(counts))
+		for _, count := range counts {
+			counters[count.CampaignID] = counter.Counts{
+				Impressions:          count.Impressions,
+				Clicks:               count.Clicks,
+				Conversions:          count.Conversions,
+				ConversionValueCents: counter.Cents(count.ConversionValue),
+			}
+		}
+
+		if err := s.counterStore.SetDay(ctx, date, counters); err != nil {
+			return fmt.Errorf("failed to rebuild Redis counters for %s: %w", dateStr, err)
+		}
 	}
 
+	if date.Before(startOfToday) {
+		for _, count := range counts {
+			if err := s.SaveDailyCounts(ctx, date, count); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Printf("Rebuilt metrics of %d campaign(s) for %s", len(counts), dateStr)
+	return nil
+}
+
+// SaveDailyCounts writes counts recomputed from the event tables to the
+// campaign's journal row for the date, keeping the row's unique user
+// estimates.
+func (s *CampaignJournalServiceImpl) SaveDailyCounts(ctx context.Context, date time.Time, counts repository.DailyEventCounts) error {
+	if err := s.ensureCampaignExists(ctx, counts.CampaignID); err != nil {
+		return fmt.Errorf("failed to ensure campaign exists: %w", err)
+	}
+
+	return s.saveJournal(ctx, counts.CampaignID, date, counts.Impressions, counts.Clicks, counts.Conversions, counts.ConversionValue, nil)
+}
+
+// saveJournal creates or updates the campaign's journal row for the date. A nil
+// uniqueUsers leaves the row's unique user columns as they are.
+func (s *CampaignJournalServiceImpl) saveJournal(ctx context.Context, campaignID uuid.UUID, date time.Time, impressionCount int64, clickCount int64, conversionCount int64, totalConversionValue decimal.Decimal, uniqueUsers *dailyUniqueUsers) error {
+	dateStr := date.Format("2006-01-02")
 	dateOnly := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
 
 	campaignJournal := &entity.CampaignJournal{
 		CampaignID:           campaignID,
 		Date:                 dateOnly,
+		NumberOfImpression:   &impressionCount,
 		NumberOfClick:        &clickCount,
 		NumberOfConversion:   &conversionCount,
 		TotalConversionValue: &totalConversionValue,
 		CreatedAt:            time.Now(),
 	}
+	setUniqueUsers(campaignJournal, uniqueUsers)
 
 	existingJournal, err := s.campaignJournalRepo.GetByCampaignAndDate(ctx, campaignID, dateOnly)
 	if err != nil && err != gorm.ErrRecordNotFound {
@@ -103,9 +223,11 @@ func (s *CampaignJournalServiceImpl) processCampaignMetrics(ctx context.Context,
 	}
 
 	if existingJournal != nil {
+		existingJournal.NumberOfImpression = &impressionCount
 		existingJournal.NumberOfClick = &clickCount
 		existingJournal.NumberOfConversion = &conversionCount
 		existingJournal.TotalConversionValue = &totalConversionValue
+		setUniqueUsers(existingJournal, uniqueUsers)
 
 		if err := s.campaignJournalRepo.Update(ctx, existingJournal); err != nil {
 			return fmt.Errorf("failed to update campaign journal: %w", err)
@@ -118,12 +240,20 @@ func (s *CampaignJournalServiceImpl) processCampaignMetrics(ctx context.Context,
 		log.Printf("Created campaign journal for campaign %s on %s", campaignID.String(), dateStr)
 	}
 
-	log.Printf("Campaign %s metrics - Clicks: %d, Conversions: %d, Total Value: %s",
-		campaignID.String(), clickCount, conversionCount, totalConversionValue.String())
-
 	return nil
 }
 
+func setUniqueUsers(campaignJournal *entity.CampaignJournal, uniqueUsers *dailyUniqueUsers) {
+	if uniqueUsers == nil {
+		return
+	}
+
+	campaignJournal.UniqueClickUsers = &uniqueUsers.clickUsers
+	campaignJournal.UniqueConversionUsers = &uniqueUsers.conversionUsers
+	campaignJournal.ClickUsersSketch = uniqueUsers.clickSketch
+	campaignJournal.ConversionUsersSketch = uniqueUsers.conversionSketch
+}
+
 func (s *CampaignJournalServiceImpl) ensureCampaignExists(ctx context.Context, campaignID uuid.UUID) error {
 	_, err := s.campaignRepo.GetByID(ctx, campaignID)
 	if err == nil {
@@ -148,42 +278,18 @@ func (s *CampaignJournalServiceImpl) ensureCampaignExists(ctx context.Context, c
 	return nil
 }
 
-func (s *CampaignJournalServiceImpl) getClickCountFromRedis(ctx context.Context, campaignID uuid.UUID, date string) (int64, error) {
-	key := fmt.Sprintf("click_count:%s:%s", campaignID.String(), date)
-	countStr, err := s.redisClient.Get(ctx, key)
-	if err != nil {
-		return 0, err
-	}
-
-	count, err := strconv.ParseInt(countStr, 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse click count: %w", err)
-	}
-
-	return count, nil
-}
-
-func (s *CampaignJournalServiceImpl) getConversionCountFromRedis(ctx context.Context, campaignID uuid.UUID, date string) (int64, error) {
-	key := fmt.Sprintf("conversion_count:%s:%s", campaignID.String(), date)
-	countStr, err := s.redisClient.Get(ctx, key)
-	if err != nil {
-		return 0, err
-	}
-
-	count, err := strconv.ParseInt(countStr, 10, 64)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse conversion count: %w", err)
+func mergeCampaignIDs(campaignIDs []uuid.UUID, others []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(campaignIDs))
+	for _, campaignID := range campaignIDs {
+		seen[campaignID] = true
 	}
 
-	return count, nil
-}
-
-func (s *CampaignJournalServiceImpl) getTotalConversionValueFromDB(ctx context.Context, campaignID uuid.UUID, date string) (decimal.Decimal, error) {
-	totalValue, err := s.conversionEventRepo.GetTotalConversionValue(ctx, campaignID, date)
-
-	if err != nil {
-		return decimal.Zero, err
+	for _, campaignID := range others {
+		if !seen[campaignID] {
+			seen[campaignID] = true
+			campaignIDs = append(campaignIDs, campaignID)
+		}
 	}
 
-	return totalValue, nil
+	return campaignIDs
 }
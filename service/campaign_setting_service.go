@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+
+	"tyrattribution/attribution"
+	"tyrattribution/entity"
+
+	"github.com/google/uuid"
+)
+
+type CampaignSettingService interface {
+	GetAttributionSettings(ctx context.Context, campaignID uuid.UUID) (*AttributionSettings, error)
+	DefaultAttributionSettings() *AttributionSettings
+	// WidestAttributionWindows returns the widest look-back and view-through
+	// windows of the defaults and every campaign. Cross-campaign lookups query
+	// with them before the candidates' campaigns are known.
+	WidestAttributionWindows(ctx context.Context) (*AttributionWindows, error)
+	UpdateCampaignSetting(ctx context.Context, campaignSetting *entity.CampaignSetting) error
+}
+
+// AttributionSettings is a campaign's effective attribution configuration,
+// with the global defaults applied to anything the campaign does not override.
+type AttributionSettings struct {
+	LookbackWindowHours    int
+	Model                  attribution.Model
+	CountedConversionTypes []string
+	ViewThroughWindowHours int
+}
+
+// AttributionWindows holds a pair of look-back and view-through windows.
+type AttributionWindows struct {
+	LookbackWindowHours    int
+	ViewThroughWindowHours int
+}
+
+// CountsConversionType reports whether conversions of the given type are
+// attributed. An empty list counts every type.
+func (s *AttributionSettings) CountsConversionType(conversionType string) bool {
+	if len(s.CountedConversionTypes) == 0 {
+		return true
+	}
+
+	for _, countedType := range s.CountedConversionTypes {
+		if countedType == conversionType {
+			return true
+		}
+	}
+
+	return false
+}
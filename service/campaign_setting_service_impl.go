@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"tyrattribution/attribution"
+	"tyrattribution/config"
+	"tyrattribution/entity"
+	"tyrattribution/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type cachedAttributionSettings struct {
+	settings  *AttributionSettings
+	expiresAt time.Time
+}
+
+type CampaignSettingServiceImpl struct {
+	campaignSettingRepo repository.CampaignSettingRepository
+	defaultModel        attribution.Model
+	config              *config.Config
+
+	mu              sync.RWMutex
+	cache           map[uuid.UUID]cachedAttributionSettings
+	widestWindows   *AttributionWindows
+	widestExpiresAt time.Time
+}
+
+func NewCampaignSettingService(
+	campaignSettingRepo repository.CampaignSettingRepository,
+	defaultModel attribution.Model,
+	cfg *config.Config,
+) CampaignSettingService {
+	return &CampaignSettingServiceImpl{
+		campaignSettingRepo: campaignSettingRepo,
+		defaultModel:        defaultModel,
+		config:              cfg,
+		cache:               make(map[uuid.UUID]cachedAttributionSettings),
+	}
+}
+
+func (s *CampaignSettingServiceImpl) GetAttributionSettings(ctx context.Context, campaignID uuid.UUID) (*AttributionSettings, error) {
+	if campaignID == uuid.Nil {
+		return s.DefaultAttributionSettings(), nil
+	}
+
+	s.mu.RLock()
+	cached, ok := s.cache[campaignID]
+	s.mu.RUnlock()
+
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.settings, nil
+	}
+
+	campaignSetting, err := s.campaignSettingRepo.GetByCampaignID(ctx, campaignID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	settings := s.resolveSettings(campaignSetting)
+
+	s.mu.Lock()
+	s.cache[campaignID] = cachedAttributionSettings{
+		settings:  settings,
+		expiresAt: time.Now().Add(time.Duration(s.config.CampaignSettingsCacheSeconds) * time.Second),
+	}
+	s.mu.Unlock()
+
+	return settings, nil
+}
+
+func (s *CampaignSettingServiceImpl) DefaultAttributionSettings() *AttributionSettings {
+	return &AttributionSettings{
+		LookbackWindowHours:    s.config.ClickEventTimeWindowHours,
+		Model:                  s.defaultModel,
+		ViewThroughWindowHours: s.config.ViewThroughWindowHours,
+	}
+}
+
+func (s *CampaignSettingServiceImpl) WidestAttributionWindows(ctx context.Context) (*AttributionWindows, error) {
+	s.mu.RLock()
+	widestWindows, expiresAt := s.widestWindows, s.widestExpiresAt
+	s.mu.RUnlock()
+
+	if widestWindows != nil && time.Now().Before(expiresAt) {
+		return widestWindows, nil
+	}
+
+	lookbackWindowHours, viewThroughWindowHours, err := s.campaignSettingRepo.GetMaxWindowHours(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	widestWindows = &AttributionWindows{
+		LookbackWindowHours:    max(lookbackWindowHours, s.config.ClickEventTimeWindowHours),
+		ViewThroughWindowHours: max(viewThroughWindowHours, s.config.ViewThroughWindowHours),
+	}
+
+	s.mu.Lock()
+	s.widestWindows = widestWindows
+	s.widestExpiresAt = time.Now().Add(time.Duration(s.config.CampaignSettingsCacheSeconds) * time.Second)
+	s.mu.Unlock()
+
+	return widestWindows, nil
+}
+
+func (s *CampaignSettingServiceImpl) UpdateCampaignSetting(ctx context.Context, campaignSetting *entity.CampaignSetting) error {
+	if err := s.campaignSettingRepo.Upsert(ctx, campaignSetting); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.cache, campaignSetting.CampaignID)
+	s.widestWindows = nil
+	s.mu.Unlock()
+
+	log.Printf("Updated attribution settings for campaign %s", campaignSetting.CampaignID.String())
+	return nil
+}
+
+func (s *CampaignSettingServiceImpl) resolveSettings(campaignSetting *entity.CampaignSetting) *AttributionSettings {
+	settings := s.DefaultAttributionSettings()
+	if campaignSetting == nil {
+		return settings
+	}
+
+	if campaignSetting.LookbackWindowHours != nil {
+		settings.LookbackWindowHours = *campaignSetting.LookbackWindowHours
+	}
+
+	if campaignSetting.ViewThroughWindowHours != nil {
+		settings.ViewThroughWindowHours = *campaignSetting.ViewThroughWindowHours
+	}
+
+	if campaignSetting.AttributionModel != nil || campaignSetting.TimeDecayHalfLifeHours != nil {
+		modelName := s.defaultModel.Name()
+		if campaignSetting.AttributionModel != nil {
+			modelName = *campaignSetting.AttributionModel
+		}
+
+		halfLifeHours := s.config.AttributionHalfLifeHours
+		if campaignSetting.TimeDecayHalfLifeHours != nil {
+			halfLifeHours = *campaignSetting.TimeDecayHalfLifeHours
+		}
+
+		model, err := attribution.NewModel(modelName, halfLifeHours)
+		if err != nil {
+			log.Printf("Invalid attribution model for campaign %s, using default: %v", campaignSetting.CampaignID.String(), err)
+		} else {
+			settings.Model = model
+		}
+	}
+
+	if campaignSetting.CountedConversionTypes != nil {
+		for _, conversionType := range strings.Split(*campaignSetting.CountedConversionTypes, ",") {
+			if conversionType = strings.TrimSpace(conversionType); conversionType != "" {
+				settings.CountedConversionTypes = append(settings.CountedConversionTypes, conversionType)
+			}
+		}
+	}
+
+	return settings
+}
+
+// modelOverrideSettingService credits every campaign with one model, whatever
+// the campaign's own setting says.
+type modelOverrideSettingService struct {
+	CampaignSettingService
+	model attribution.Model
+}
+
+// NewModelOverrideSettingService wraps a CampaignSettingService so that every
+// campaign uses model. Replays use it to re-attribute history with a
+// different model.
+func NewModelOverrideSettingService(campaignSettingService CampaignSettingService, model attribution.Model) CampaignSettingService {
+	return &modelOverrideSettingService{
+		CampaignSettingService: campaignSettingService,
+		model:                  model,
+	}
+}
+
+func (s *modelOverrideSettingService) GetAttributionSettings(ctx context.Context, campaignID uuid.UUID) (*AttributionSettings, error) {
+	settings, err := s.CampaignSettingService.GetAttributionSettings(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := *settings
+	overridden.Model = s.model
+	return &overridden, nil
+}
+
+func (s *modelOverrideSettingService) DefaultAttributionSettings() *AttributionSettings {
+	settings := s.CampaignSettingService.DefaultAttributionSettings()
+	settings.Model = s.model
+	return settings
+}
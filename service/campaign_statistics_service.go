@@ -20,8 +20,20 @@ type CampaignStatisticsResponse struct {
 
 type CampaignStatisticsDataItem struct {
 	Period           string          `json:"period"`
+	TotalImpressions int64           `json:"total_impressions"`
 	TotalClicks      int64           `json:"total_clicks"`
 	TotalConversions int64           `json:"total_conversions"`
 	TotalValue       decimal.Decimal `json:"total_value"`
 	ConversionRate   float64         `json:"conversion_rate"`
+	// UniqueUsers counts the distinct users who clicked, and
+	// UniqueConvertingUsers those who converted. Both are HyperLogLog
+	// estimates, with a standard error of about 0.8%.
+	UniqueUsers           int64   `json:"unique_users"`
+	UniqueConvertingUsers int64   `json:"unique_converting_users"`
+	ClicksPerUser         float64 `json:"clicks_per_user"`
+	// AttributedConversions sums the campaign's fractional shares of
+	// conversions, including those assigned to other campaigns, and
+	// AttributedValue the conversion value credited with them.
+	AttributedConversions decimal.Decimal `json:"attributed_conversions"`
+	AttributedValue       decimal.Decimal `json:"attributed_value"`
 }
@@ -4,31 +4,29 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"strconv"
 	"time"
 
-	"tyrattribution/redis"
+	"tyrattribution/counter"
 	"tyrattribution/repository"
 
 	"github.com/google/uuid"
-	"github.com/shopspring/decimal"
 )
 
 type CampaignStatisticsServiceImpl struct {
 	campaignJournalRepo repository.CampaignJournalRepository
 	campaignStatsRepo   repository.CampaignStatisticsRepository
-	redisClient         redis.Client
+	counterStore        counter.Store
 }
 
 func NewCampaignStatisticsService(
 	campaignJournalRepo repository.CampaignJournalRepository,
 	campaignStatsRepo repository.CampaignStatisticsRepository,
-	redisClient redis.Client,
+	counterStore counter.Store,
 ) CampaignStatisticsService {
 	return &CampaignStatisticsServiceImpl{
 		campaignJournalRepo: campaignJournalRepo,
 		campaignStatsRepo:   campaignStatsRepo,
-		redisClient:         redisClient,
+		counterStore:        counterStore,
 	}
 }
 
@@ -55,7 +53,7 @@ func (s *CampaignStatisticsServiceImpl) GetCampaignStatistics(ctx context.Contex
 	if groupByType == repository.GroupByDaily {
 		todayData, err = s.getTodayData(ctx, campaignID)
 		if err != nil {
-			log.Printf("Failed to get today's data from Redis: %v", err)
+			log.Printf("Failed to get today's data: %v", err)
 			todayData = nil
 		}
 	}
@@ -63,8 +61,18 @@ func (s *CampaignStatisticsServiceImpl) GetCampaignStatistics(ctx context.Contex
 	// Convert repository data to service data
 	serviceHistoricalData := s.convertToServiceData(historicalData)
 
+	if groupByType != repository.GroupByDaily {
+		if err := s.mergeUniqueUsers(ctx, campaignID, groupByType, serviceHistoricalData); err != nil {
+			log.Printf("Failed to merge unique user sketches: %v", err)
+		}
+	}
+
 	combinedData := s.combineData(serviceHistoricalData, todayData, groupByType)
 
+	if err := s.mergeAttributedConversions(ctx, campaignID, groupByType, combinedData); err != nil {
+		log.Printf("Failed to merge attributed conversions: %v", err)
+	}
+
 	return &CampaignStatisticsResponse{
 		CampaignID: campaignID.String(),
 		GroupBy:    groupBy,
@@ -73,41 +81,102 @@ func (s *CampaignStatisticsServiceImpl) GetCampaignStatistics(ctx context.Contex
 }
 
 func (s *CampaignStatisticsServiceImpl) getTodayData(ctx context.Context, campaignID uuid.UUID) (*CampaignStatisticsDataItem, error) {
-	today := time.Now().Format("2006-01-02")
-
-	clickKey := fmt.Sprintf("click_count:%s:%s", campaignID.String(), today)
-	clickCountStr, err := s.redisClient.Get(ctx, clickKey)
-	var clickCount int64 = 0
-	if err == nil {
-		if parsed, parseErr := strconv.ParseInt(clickCountStr, 10, 64); parseErr == nil {
-			clickCount = parsed
-		}
+	now := time.Now()
+	today := now.Format("2006-01-02")
+
+	counts, err := s.counterStore.GetDay(ctx, campaignID, now)
+	if err != nil {
+		log.Printf("Redis counters unavailable, counting today's data from Postgres: %v", err)
+		return s.getTodayDataFromEvents(ctx, campaignID, now)
+	}
+
+	conversionRate := s.calculateConversionRate(counts.Clicks, counts.Conversions)
+
+	return &CampaignStatisticsDataItem{
+		Period:                today,
+		TotalImpressions:      counts.Impressions,
+		TotalClicks:           counts.Clicks,
+		TotalConversions:      counts.Conversions,
+		TotalValue:            counter.FromCents(counts.ConversionValueCents),
+		ConversionRate:        conversionRate,
+		UniqueUsers:           counts.UniqueClickUsers,
+		UniqueConvertingUsers: counts.UniqueConversionUsers,
+		ClicksPerUser:         s.calculateClicksPerUser(counts.Clicks, counts.UniqueClickUsers),
+	}, nil
+}
+
+// getTodayDataFromEvents counts today's data from the event tables, for when
+// the Redis counters cannot be read. Unique users are exact counts here.
+func (s *CampaignStatisticsServiceImpl) getTodayDataFromEvents(ctx context.Context, campaignID uuid.UUID, now time.Time) (*CampaignStatisticsDataItem, error) {
+	counts, err := s.campaignJournalRepo.CountCampaignDailyEvents(ctx, campaignID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count today's events: %w", err)
+	}
+
+	return &CampaignStatisticsDataItem{
+		Period:                now.Format("2006-01-02"),
+		TotalImpressions:      counts.Impressions,
+		TotalClicks:           counts.Clicks,
+		TotalConversions:      counts.Conversions,
+		TotalValue:            counts.ConversionValue,
+		ConversionRate:        s.calculateConversionRate(counts.Clicks, counts.Conversions),
+		UniqueUsers:           counts.ClickUsers,
+		UniqueConvertingUsers: counts.ConversionUsers,
+		ClicksPerUser:         s.calculateClicksPerUser(counts.Clicks, counts.ClickUsers),
+	}, nil
+}
+
+// mergeUniqueUsers sets the unique users of each weekly or monthly period by
+// merging the sketches of the period's days, since daily estimates cannot be
+// added up.
+func (s *CampaignStatisticsServiceImpl) mergeUniqueUsers(ctx context.Context, campaignID uuid.UUID, groupBy repository.GroupBy, data []CampaignStatisticsDataItem) error {
+	sketches, err := s.campaignStatsRepo.GetUserSketches(ctx, campaignID, groupBy)
+	if err != nil {
+		return err
+	}
+
+	clickSketches := make(map[string][][]byte)
+	conversionSketches := make(map[string][][]byte)
+	for _, sketch := range sketches {
+		clickSketches[sketch.Period] = append(clickSketches[sketch.Period], sketch.ClickUsersSketch)
+		conversionSketches[sketch.Period] = append(conversionSketches[sketch.Period], sketch.ConversionUsersSketch)
 	}
 
-	conversionKey := fmt.Sprintf("conversion_count:%s:%s", campaignID.String(), today)
-	conversionCountStr, err := s.redisClient.Get(ctx, conversionKey)
-	var conversionCount int64 = 0
-	if err == nil {
-		if parsed, parseErr := strconv.ParseInt(conversionCountStr, 10, 64); parseErr == nil {
-			conversionCount = parsed
+	for i := range data {
+		item := &data[i]
+
+		if item.UniqueUsers, err = s.counterStore.CountUnion(ctx, clickSketches[item.Period]); err != nil {
+			return err
+		}
+		if item.UniqueConvertingUsers, err = s.counterStore.CountUnion(ctx, conversionSketches[item.Period]); err != nil {
+			return err
 		}
+		item.ClicksPerUser = s.calculateClicksPerUser(item.TotalClicks, item.UniqueUsers)
 	}
 
-	totalValue, err := s.campaignStatsRepo.GetTodayConversionValue(ctx, campaignID, time.Now())
+	return nil
+}
+
+// mergeAttributedConversions sets the fractional conversions and credited value
+// of each period from the campaign's attribution credits.
+func (s *CampaignStatisticsServiceImpl) mergeAttributedConversions(ctx context.Context, campaignID uuid.UUID, groupBy repository.GroupBy, data []CampaignStatisticsDataItem) error {
+	attributedConversions, err := s.campaignStatsRepo.GetAttributedConversions(ctx, campaignID, groupBy)
 	if err != nil {
-		log.Printf("Failed to get today's total conversion value: %v", err)
-		totalValue = decimal.Zero
+		return err
 	}
 
-	conversionRate := s.calculateConversionRate(clickCount, conversionCount)
+	byPeriod := make(map[string]repository.PeriodAttributedConversions, len(attributedConversions))
+	for _, attributed := range attributedConversions {
+		byPeriod[attributed.Period] = attributed
+	}
 
-	return &CampaignStatisticsDataItem{
-		Period:           today,
-		TotalClicks:      clickCount,
-		TotalConversions: conversionCount,
-		TotalValue:       totalValue,
-		ConversionRate:   conversionRate,
-	}, nil
+	for i := range data {
+		attributed := byPeriod[data[i].Period]
+		data[i].AttributedConversions = attributed.FractionalConversions
+		data[i].AttributedValue = attributed.CreditedValue
+	}
+
+	return nil
 }
 
 func (s *CampaignStatisticsServiceImpl) convertToServiceData(repoData []repository.CampaignStatisticsData) []CampaignStatisticsDataItem {
@@ -115,18 +184,22 @@ func (s *CampaignStatisticsServiceImpl) convertToServiceData(repoData []reposito
 	for _, data := range repoData {
 		conversionRate := s.calculateConversionRate(data.TotalClicks, data.TotalConversions)
 		result = append(result, CampaignStatisticsDataItem{
-			Period:           data.Period,
-			TotalClicks:      data.TotalClicks,
-			TotalConversions: data.TotalConversions,
-			TotalValue:       data.TotalValue,
-			ConversionRate:   conversionRate,
+			Period:                data.Period,
+			TotalImpressions:      data.TotalImpressions,
+			TotalClicks:           data.TotalClicks,
+			TotalConversions:      data.TotalConversions,
+			TotalValue:            data.TotalValue,
+			ConversionRate:        conversionRate,
+			UniqueUsers:           data.UniqueClickUsers,
+			UniqueConvertingUsers: data.UniqueConversionUsers,
+			ClicksPerUser:         s.calculateClicksPerUser(data.TotalClicks, data.UniqueClickUsers),
 		})
 	}
 	return result
 }
 
 func (s *CampaignStatisticsServiceImpl) combineData(historical []CampaignStatisticsDataItem, today *CampaignStatisticsDataItem, groupBy repository.GroupBy) []CampaignStatisticsDataItem {
-	if today == nil || (today.TotalClicks == 0 && today.TotalConversions == 0) {
+	if today == nil || (today.TotalImpressions == 0 && today.TotalClicks == 0 && today.TotalConversions == 0) {
 		return historical
 	}
 
@@ -145,6 +218,7 @@ func (s *CampaignStatisticsServiceImpl) combineData(historical []CampaignStatist
 	found := false
 	for i, item := range historical {
 		if item.Period == todayPeriod {
+			historical[i].TotalImpressions += today.TotalImpressions
 			historical[i].TotalClicks += today.TotalClicks
 			historical[i].TotalConversions += today.TotalConversions
 			historical[i].TotalValue = historical[i].TotalValue.Add(today.TotalValue)
@@ -156,11 +230,15 @@ func (s *CampaignStatisticsServiceImpl) combineData(historical []CampaignStatist
 
 	if !found {
 		todayData := CampaignStatisticsDataItem{
-			Period:           todayPeriod,
-			TotalClicks:      today.TotalClicks,
-			TotalConversions: today.TotalConversions,
-			TotalValue:       today.TotalValue,
-			ConversionRate:   s.calculateConversionRate(today.TotalClicks, today.TotalConversions),
+			Period:                todayPeriod,
+			TotalImpressions:      today.TotalImpressions,
+			TotalClicks:           today.TotalClicks,
+			TotalConversions:      today.TotalConversions,
+			TotalValue:            today.TotalValue,
+			ConversionRate:        s.calculateConversionRate(today.TotalClicks, today.TotalConversions),
+			UniqueUsers:           today.UniqueUsers,
+			UniqueConvertingUsers: today.UniqueConvertingUsers,
+			ClicksPerUser:         today.ClicksPerUser,
 		}
 
 		historical = append([]CampaignStatisticsDataItem{todayData}, historical...)
@@ -188,3 +266,10 @@ func (s *CampaignStatisticsServiceImpl) calculateConversionRate(clicks, conversi
 	}
 	return (float64(conversions) / float64(clicks)) * 100.0
 }
+
+func (s *CampaignStatisticsServiceImpl) calculateClicksPerUser(clicks, uniqueUsers int64) float64 {
+	if uniqueUsers == 0 {
+		return 0.0
+	}
+	return float64(clicks) / float64(uniqueUsers)
+}
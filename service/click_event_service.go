@@ -10,6 +10,9 @@ import (
 
 type ClickEventService interface {
 	CreateClickEvent(ctx context.Context, clickEvent *entity.ClickEvent) error
+	CreateClickEvents(ctx context.Context, clickEvents []*entity.ClickEvent) error
+	GetClickEventByID(ctx context.Context, clickID uuid.UUID) (*entity.ClickEvent, error)
 	GetClickCountByCampaign(ctx context.Context, campaignID uuid.UUID, date time.Time) (int64, error)
-	GetClickEventsByCampaignUserSourceWithinTimeWindow(ctx context.Context, campaignID uuid.UUID, userID uuid.UUID, source string, clickDate time.Time, timeWindowHours int) (*entity.ClickEvent, error)
+	GetClickEventsByCampaignUserSourceWithinTimeWindow(ctx context.Context, campaignID uuid.UUID, userID uuid.UUID, source string, conversionDate time.Time, timeWindowHours int, minDelaySeconds int) ([]entity.ClickEvent, error)
+	GetClickEventsByUserWithinTimeWindow(ctx context.Context, userID uuid.UUID, conversionDate time.Time, timeWindowHours int, minDelaySeconds int) ([]entity.ClickEvent, error)
 }
\ No newline at end of file
@@ -2,74 +2,114 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"log"
-	"strconv"
 	"time"
+	"tyrattribution/counter"
 	"tyrattribution/entity"
-	"tyrattribution/redis"
 	"tyrattribution/repository"
 
 	"github.com/google/uuid"
 )
 
 type ClickEventServiceImpl struct {
-	clickEventRepository repository.ClickEventRepository
-	redisClient          redis.Client
+	clickEventRepository         repository.ClickEventRepository
+	pendingAttributionRepository repository.PendingAttributionRepository
+	counterStore                 counter.Store
 }
 
-func NewClickEventService(clickEventRepository repository.ClickEventRepository, redisClient redis.Client) ClickEventService {
+func NewClickEventService(clickEventRepository repository.ClickEventRepository, pendingAttributionRepository repository.PendingAttributionRepository, counterStore counter.Store) ClickEventService {
 	return &ClickEventServiceImpl{
-		clickEventRepository: clickEventRepository,
-		redisClient:          redisClient,
+		clickEventRepository:         clickEventRepository,
+		pendingAttributionRepository: pendingAttributionRepository,
+		counterStore:                 counterStore,
 	}
 }
 
 func (s *ClickEventServiceImpl) CreateClickEvent(ctx context.Context, clickEvent *entity.ClickEvent) error {
-	if err := s.clickEventRepository.Create(ctx, clickEvent); err != nil {
+	created, err := s.clickEventRepository.Create(ctx, clickEvent)
+	if err != nil {
 		return err
 	}
 
-	date := clickEvent.ClickDate.Format("2006-01-02")
-	counterKey := fmt.Sprintf("click_count:%s:%s", clickEvent.CampaignID.String(), date)
+	if !created {
+		log.Printf("Click event %s already exists, skipping counter increment", clickEvent.ClickID.String())
+		return nil
+	}
+
+	updates := newCounterUpdates()
+	countClick(updates, clickEvent)
+	updates.apply(ctx, s.counterStore)
+
+	s.triggerReattribution(ctx, []uuid.UUID{clickEvent.UserID})
+
+	return nil
+}
 
-	count, err := s.redisClient.Incr(ctx, counterKey)
+// CreateClickEvents stores a batch of clicks in one bulk insert and counts the
+// new ones with a single Redis pipeline.
+func (s *ClickEventServiceImpl) CreateClickEvents(ctx context.Context, clickEvents []*entity.ClickEvent) error {
+	created, err := s.clickEventRepository.CreateBatch(ctx, clickEvents)
 	if err != nil {
-		log.Printf("Failed to increment Redis counter for key %s: %v", counterKey, err)
-	} else {
-		if count == 1 {
-			nextDay := time.Now().AddDate(0, 0, 1)
-			endOfNextDay := time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 23, 59, 59, 0, nextDay.Location())
-			secondsUntilExpiry := int(time.Until(endOfNextDay).Seconds())
-
-			if expireErr := s.redisClient.Expire(ctx, counterKey, secondsUntilExpiry); expireErr != nil {
-				log.Printf("Failed to set expiration for Redis key %s: %v", counterKey, expireErr)
-			}
+		return err
+	}
+
+	updates := newCounterUpdates()
+	userIDs := make([]uuid.UUID, 0, len(created))
+	seenUsers := make(map[uuid.UUID]bool, len(created))
+	for _, clickEvent := range created {
+		countClick(updates, clickEvent)
+
+		if !seenUsers[clickEvent.UserID] {
+			seenUsers[clickEvent.UserID] = true
+			userIDs = append(userIDs, clickEvent.UserID)
 		}
-		log.Printf("Incremented click counter for campaign %s on %s: %d", clickEvent.CampaignID.String(), date, count)
 	}
 
+	updates.apply(ctx, s.counterStore)
+	s.triggerReattribution(ctx, userIDs)
+
+	log.Printf("Stored %d new click events from batch of %d", len(created), len(clickEvents))
 	return nil
 }
 
-func (s *ClickEventServiceImpl) GetClickCountByCampaign(ctx context.Context, campaignID uuid.UUID, date time.Time) (int64, error) {
-	dateStr := date.Format("2006-01-02")
-	counterKey := fmt.Sprintf("click_count:%s:%s", campaignID.String(), dateStr)
-
-	countStr, err := s.redisClient.Get(ctx, counterKey)
+// triggerReattribution makes the queued conversions of users who just clicked
+// due now, so the sweeper tries them against the new clicks on its next run.
+func (s *ClickEventServiceImpl) triggerReattribution(ctx context.Context, userIDs []uuid.UUID) {
+	rescheduled, err := s.pendingAttributionRepository.RescheduleUsers(ctx, userIDs, time.Now())
 	if err != nil {
-		return 0, nil
+		log.Printf("Failed to reschedule pending conversions for new clicks: %v", err)
+		return
+	}
+
+	if rescheduled > 0 {
+		log.Printf("Rescheduled %d pending conversion(s) after new clicks", rescheduled)
 	}
+}
+
+func (s *ClickEventServiceImpl) GetClickEventByID(ctx context.Context, clickID uuid.UUID) (*entity.ClickEvent, error) {
+	return s.clickEventRepository.GetByID(ctx, clickID)
+}
 
-	count, err := strconv.ParseInt(countStr, 10, 64)
+func (s *ClickEventServiceImpl) GetClickCountByCampaign(ctx context.Context, campaignID uuid.UUID, date time.Time) (int64, error) {
+	count, err := s.counterStore.Get(ctx, counter.NewKey(counter.Clicks, campaignID, date))
 	if err != nil {
-		log.Printf("Failed to parse Redis counter value for key %s: %v", counterKey, err)
+		log.Printf("Failed to get click counter for campaign %s: %v", campaignID.String(), err)
 		return 0, nil
 	}
 
 	return count, nil
 }
 
-func (s *ClickEventServiceImpl) GetClickEventsByCampaignUserSourceWithinTimeWindow(ctx context.Context, campaignID uuid.UUID, userID uuid.UUID, source string, clickDate time.Time, timeWindowHours int) (*entity.ClickEvent, error) {
-	return s.clickEventRepository.GetClickEventsByCampaignUserSourceWithinTimeWindow(ctx, campaignID, userID, source, clickDate, timeWindowHours)
+func (s *ClickEventServiceImpl) GetClickEventsByCampaignUserSourceWithinTimeWindow(ctx context.Context, campaignID uuid.UUID, userID uuid.UUID, source string, conversionDate time.Time, timeWindowHours int, minDelaySeconds int) ([]entity.ClickEvent, error) {
+	return s.clickEventRepository.GetClickEventsByCampaignUserSourceWithinTimeWindow(ctx, campaignID, userID, source, conversionDate, timeWindowHours, minDelaySeconds)
+}
+
+func (s *ClickEventServiceImpl) GetClickEventsByUserWithinTimeWindow(ctx context.Context, userID uuid.UUID, conversionDate time.Time, timeWindowHours int, minDelaySeconds int) ([]entity.ClickEvent, error) {
+	return s.clickEventRepository.GetClickEventsByUserWithinTimeWindow(ctx, userID, conversionDate, timeWindowHours, minDelaySeconds)
+}
+
+// countClick adds a new click and its user to the counter updates.
+func countClick(updates *counterUpdates, clickEvent *entity.ClickEvent) {
+	updates.increment(counter.NewKey(counter.Clicks, clickEvent.CampaignID, clickEvent.ClickDate), 1)
+	updates.addUser(counter.NewKey(counter.ClickUsers, clickEvent.CampaignID, clickEvent.ClickDate), clickEvent.UserID)
 }
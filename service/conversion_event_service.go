@@ -3,8 +3,18 @@ package service
 import (
 	"context"
 	"tyrattribution/entity"
+
+	"github.com/google/uuid"
 )
 
 type ConversionEventService interface {
 	CreateConversionEvent(ctx context.Context, conversionEvent *entity.ConversionEvent) error
+	CreateConversionEvents(ctx context.Context, conversionEvents []*entity.ConversionEvent) error
+	// ReattributePending retries attribution for up to limit queued
+	// conversions that are due and returns how many were processed without
+	// error.
+	ReattributePending(ctx context.Context, limit int) (int, error)
+	// ResetAttribution clears the stored attribution of the conversions so that
+	// reprocessing them attributes them again.
+	ResetAttribution(ctx context.Context, conversionIDs []uuid.UUID) error
 }
\ No newline at end of file
@@ -5,83 +5,661 @@ import (
 	"fmt"
 	"log"
 	"time"
+	"tyrattribution/attribution"
 	"tyrattribution/config"
+	"tyrattribution/counter"
 	"tyrattribution/entity"
-	"tyrattribution/redis"
 	"tyrattribution/repository"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// attributionOutcome is what attribute did with a conversion.
+type attributionOutcome int
+
+const (
+	// attributionUncounted means the conversion is not counted, because its
+	// type is not counted or it repeats an order already counted.
+	attributionUncounted attributionOutcome = iota
+	attributionCounted
+	// attributionUnmatched means no click or impression matched the
+	// conversion yet.
+	attributionUnmatched
 )
 
 type ConversionEventServiceImpl struct {
-	conversionEventRepository repository.ConversionEventRepository
-	clickEventService         ClickEventService
-	redisClient               redis.Client
-	config                    *config.Config
+	conversionEventRepository    repository.ConversionEventRepository
+	pendingAttributionRepository repository.PendingAttributionRepository
+	campaignJournalRepository    repository.CampaignJournalRepository
+	clickEventService            ClickEventService
+	impressionEventService       ImpressionEventService
+	campaignSettingService       CampaignSettingService
+	counterStore                 counter.Store
+	config                       *config.Config
 }
 
-func NewConversionEventService(conversionEventRepository repository.ConversionEventRepository, clickEventService ClickEventService, redisClient redis.Client, cfg *config.Config) ConversionEventService {
+func NewConversionEventService(conversionEventRepository repository.ConversionEventRepository, pendingAttributionRepository repository.PendingAttributionRepository, campaignJournalRepository repository.CampaignJournalRepository, clickEventService ClickEventService, impressionEventService ImpressionEventService, campaignSettingService CampaignSettingService, counterStore counter.Store, cfg *config.Config) ConversionEventService {
 	return &ConversionEventServiceImpl{
-		conversionEventRepository: conversionEventRepository,
-		clickEventService:         clickEventService,
-		redisClient:               redisClient,
-		config:                    cfg,
+		conversionEventRepository:    conversionEventRepository,
+		pendingAttributionRepository: pendingAttributionRepository,
+		campaignJournalRepository:    campaignJournalRepository,
+		clickEventService:            clickEventService,
+		impressionEventService:       impressionEventService,
+		campaignSettingService:       campaignSettingService,
+		counterStore:                 counterStore,
+		config:                       cfg,
 	}
 }
 
 func (s *ConversionEventServiceImpl) CreateConversionEvent(ctx context.Context, conversionEvent *entity.ConversionEvent) error {
-	if err := s.conversionEventRepository.Create(ctx, conversionEvent); err != nil {
+	// A click_id supplied by the client is only a hint until the click is
+	// confirmed below, so the conversion is stored unattributed first.
+	suppliedClickID := conversionEvent.ClickID
+	conversionEvent.ClickID = nil
+
+	created, err := s.conversionEventRepository.Create(ctx, conversionEvent)
+	if err != nil {
 		return err
 	}
 
-	timeWindowHours := s.config.ClickEventTimeWindowHours
-	matchedClick, err := s.clickEventService.GetClickEventsByCampaignUserSourceWithinTimeWindow(
-		ctx,
-		conversionEvent.CampaignID,
-		conversionEvent.UserID,
-		conversionEvent.Source,
-		conversionEvent.ConversionDate,
-		timeWindowHours,
-	)
+	if !created {
+		conversionEvent, err = s.loadUnattributed(ctx, conversionEvent.ConversionID)
+		if err != nil || conversionEvent == nil {
+			return err
+		}
+	}
 
+	outcome, err := s.attribute(ctx, conversionEvent, suppliedClickID)
 	if err != nil {
-		log.Printf("Error checking for matched click event: %v", err)
-		return nil
+		return err
 	}
 
-	if matchedClick != nil {
-		conversionEvent.ClickID = &matchedClick.ClickID
+	switch outcome {
+	case attributionCounted:
+		updates := newCounterUpdates()
+		countConversion(updates, conversionEvent)
+		updates.apply(ctx, s.counterStore)
+	case attributionUnmatched:
+		return s.deferAttribution(ctx, conversionEvent, suppliedClickID)
+	}
 
-		if err := s.conversionEventRepository.Update(ctx, conversionEvent); err != nil {
-			log.Printf("Failed to update conversion event with ClickID: %v", err)
-		} else {
-			log.Printf("Attributed conversion %s to click %s", conversionEvent.ConversionID.String(), matchedClick.ClickID.String())
-			s.incrementConversionCounter(ctx, conversionEvent)
+	return nil
+}
+
+// CreateConversionEvents stores a batch of conversions in one bulk insert,
+// attributes them in order and counts them with a single Redis pipeline. When
+// attribution fails part way, the conversions attributed so far are still
+// counted before the error is returned, so reprocessing the batch does not
+// lose or double their counts.
+func (s *ConversionEventServiceImpl) CreateConversionEvents(ctx context.Context, conversionEvents []*entity.ConversionEvent) error {
+	suppliedClickIDs := make(map[uuid.UUID]*uuid.UUID, len(conversionEvents))
+	for _, conversionEvent := range conversionEvents {
+		suppliedClickIDs[conversionEvent.ConversionID] = conversionEvent.ClickID
+		conversionEvent.ClickID = nil
+	}
+
+	created, err := s.conversionEventRepository.CreateBatch(ctx, conversionEvents)
+	if err != nil {
+		return err
+	}
+
+	createdIDs := make(map[uuid.UUID]bool, len(created))
+	for _, conversionEvent := range created {
+		createdIDs[conversionEvent.ConversionID] = true
+	}
+
+	updates := newCounterUpdates()
+	processed := make(map[uuid.UUID]bool, len(conversionEvents))
+	var attributeErr error
+
+	for _, conversionEvent := range conversionEvents {
+		conversionID := conversionEvent.ConversionID
+		if processed[conversionID] {
+			continue
 		}
-	} else {
-		log.Printf("No matching click event found for conversion %s within %d hour window", conversionEvent.ConversionID.String(), timeWindowHours)
+		processed[conversionID] = true
+
+		if !createdIDs[conversionID] {
+			conversionEvent, attributeErr = s.loadUnattributed(ctx, conversionID)
+			if attributeErr != nil {
+				break
+			}
+			if conversionEvent == nil {
+				continue
+			}
+		}
+
+		outcome, err := s.attribute(ctx, conversionEvent, suppliedClickIDs[conversionID])
+		if err != nil {
+			attributeErr = err
+			break
+		}
+
+		switch outcome {
+		case attributionCounted:
+			countConversion(updates, conversionEvent)
+		case attributionUnmatched:
+			attributeErr = s.deferAttribution(ctx, conversionEvent, suppliedClickIDs[conversionID])
+		}
+		if attributeErr != nil {
+			break
+		}
+	}
+
+	updates.apply(ctx, s.counterStore)
+
+	log.Printf("Stored %d new conversion events from batch of %d", len(created), len(conversionEvents))
+	return attributeErr
+}
+
+// ReattributePending retries attribution for queued conversions that are due.
+// Conversions that still match nothing stay queued, with a growing delay,
+// until their look-back window has closed. Late matches are counted in Redis
+// while the conversion date's counter is live, and added to the campaign
+// journal row when that day has already been written.
+func (s *ConversionEventServiceImpl) ReattributePending(ctx context.Context, limit int) (int, error) {
+	now := time.Now()
+	updates := newCounterUpdates()
+	retryDelay := time.Duration(s.config.ReattributionDelaySeconds) * time.Second
+
+	processed, err := s.pendingAttributionRepository.ProcessDue(ctx, now, limit, retryDelay, func(pendingAttributions []entity.PendingAttribution) []error {
+		errs := make([]error, len(pendingAttributions))
+
+		for i := range pendingAttributions {
+			pendingAttribution := &pendingAttributions[i]
+
+			conversionEvent, err := s.loadUnattributed(ctx, pendingAttribution.ConversionID)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			if conversionEvent == nil {
+				continue
+			}
+
+			outcome, err := s.attribute(ctx, conversionEvent, pendingAttribution.ClickID)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			switch outcome {
+			case attributionCounted:
+				log.Printf("Conversion %s attributed late, after %d pass(es)", conversionEvent.ConversionID.String(), pendingAttribution.Attempts+1)
+				if counter.IsLive(conversionEvent.ConversionDate, now) {
+					countConversion(updates, conversionEvent)
+				}
+				s.addToJournal(ctx, conversionEvent)
+			case attributionUnmatched:
+				if !pendingAttribution.ExpiresAt.After(now) {
+					log.Printf("Look-back window closed for conversion %s, leaving it unattributed", conversionEvent.ConversionID.String())
+					continue
+				}
+				pendingAttribution.RetryAt = s.nextReattribution(now, pendingAttribution)
+			}
+		}
+
+		return errs
+	})
+
+	updates.apply(ctx, s.counterStore)
+
+	return processed, err
+}
+
+// deferAttribution queues an unmatched conversion for the reattribution
+// sweeper, since a click inside its look-back window may still arrive. A
+// conversion whose window has already closed is not queued. Across campaigns
+// the widest window of any campaign applies.
+func (s *ConversionEventServiceImpl) deferAttribution(ctx context.Context, conversionEvent *entity.ConversionEvent, suppliedClickID *uuid.UUID) error {
+	if s.config.ReattributionDelaySeconds <= 0 {
+		return nil
+	}
+
+	windows := s.candidateWindows(ctx, s.attributionSettings(ctx, conversionEvent.CampaignID))
+	expiresAt := conversionEvent.ConversionDate.Add(time.Duration(windows.LookbackWindowHours) * time.Hour)
+	now := time.Now()
+	if !expiresAt.After(now) {
+		return nil
+	}
+
+	retryAt := now.Add(time.Duration(s.config.ReattributionDelaySeconds) * time.Second)
+	if retryAt.After(expiresAt) {
+		retryAt = expiresAt
+	}
+
+	pendingAttribution := &entity.PendingAttribution{
+		ConversionID: conversionEvent.ConversionID,
+		UserID:       conversionEvent.UserID,
+		ClickID:      suppliedClickID,
+		RetryAt:      retryAt,
+		ExpiresAt:    expiresAt,
+	}
+
+	if err := s.pendingAttributionRepository.Create(ctx, pendingAttribution); err != nil {
+		return fmt.Errorf("failed to queue conversion %s for delayed attribution: %w", conversionEvent.ConversionID.String(), err)
 	}
 
 	return nil
 }
 
-func (s *ConversionEventServiceImpl) incrementConversionCounter(ctx context.Context, conversionEvent *entity.ConversionEvent) {
-	date := conversionEvent.ConversionDate.Format("2006-01-02")
-	counterKey := fmt.Sprintf("conversion_count:%s:%s", conversionEvent.CampaignID.String(), date)
+// nextReattribution doubles the delay after every unmatched pass, up to the
+// configured maximum, and makes one last pass when the look-back window
+// closes.
+func (s *ConversionEventServiceImpl) nextReattribution(now time.Time, pendingAttribution *entity.PendingAttribution) time.Time {
+	delay := time.Duration(s.config.ReattributionDelaySeconds) * time.Second
+	maxDelay := time.Duration(s.config.ReattributionMaxDelaySeconds) * time.Second
 
-	count, err := s.redisClient.Incr(ctx, counterKey)
+	for i := 0; i < pendingAttribution.Attempts+1 && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	retryAt := now.Add(delay)
+	if retryAt.After(pendingAttribution.ExpiresAt) {
+		return pendingAttribution.ExpiresAt
+	}
+
+	return retryAt
+}
+
+// addToJournal adds a late-attributed conversion to its campaign's journal row
+// if the conversion date has already been journaled. Counts the journal job
+// writes later already include the conversion.
+func (s *ConversionEventServiceImpl) addToJournal(ctx context.Context, conversionEvent *entity.ConversionEvent) {
+	value := decimal.Zero
+	if conversionEvent.Value != nil {
+		value = *conversionEvent.Value
+	}
+
+	updated, err := s.campaignJournalRepository.AddConversion(ctx, conversionEvent.CampaignID, conversionEvent.ConversionDate, value)
 	if err != nil {
-		log.Printf("Failed to increment Redis conversion counter for key %s: %v", counterKey, err)
+		log.Printf("Failed to add late conversion %s to campaign journal: %v", conversionEvent.ConversionID.String(), err)
 		return
 	}
 
-	if count == 1 {
-		nextDay := time.Now().AddDate(0, 0, 1)
-		endOfNextDay := time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 23, 59, 59, 0, nextDay.Location())
-		secondsUntilExpiry := int(time.Until(endOfNextDay).Seconds())
+	if updated {
+		log.Printf("Added late conversion %s to campaign %s journal for %s",
+			conversionEvent.ConversionID.String(), conversionEvent.CampaignID.String(), conversionEvent.ConversionDate.Format("2006-01-02"))
+	}
+}
+
+func (s *ConversionEventServiceImpl) ResetAttribution(ctx context.Context, conversionIDs []uuid.UUID) error {
+	if err := s.conversionEventRepository.ResetAttribution(ctx, conversionIDs); err != nil {
+		return fmt.Errorf("failed to reset attribution: %w", err)
+	}
+
+	return nil
+}
+
+// loadUnattributed handles a redelivered conversion. Conversions are only
+// attributed, and counted, once, so it returns nil when the stored conversion
+// is already attributed and otherwise the stored conversion for a retry.
+func (s *ConversionEventServiceImpl) loadUnattributed(ctx context.Context, conversionID uuid.UUID) (*entity.ConversionEvent, error) {
+	existing, err := s.conversionEventRepository.GetByID(ctx, conversionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.ClickID != nil || existing.ImpressionID != nil {
+		log.Printf("Conversion event %s already attributed, skipping", conversionID.String())
+		return nil, nil
+	}
+
+	return existing, nil
+}
+
+// attribute credits a stored conversion to its clicks, or to an impression when
+// no click matches, and reports whether the conversion should be counted or
+// matched nothing. Across campaigns, each candidate is held to its own
+// campaign's window and counted types, and the model of the latest candidate's
+// campaign credits them.
+func (s *ConversionEventServiceImpl) attribute(ctx context.Context, conversionEvent *entity.ConversionEvent, suppliedClickID *uuid.UUID) (attributionOutcome, error) {
+	settings := s.attributionSettings(ctx, conversionEvent.CampaignID)
+
+	if !settings.CountsConversionType(conversionEvent.Type) {
+		log.Printf("Conversion %s has uncounted type %s, skipping attribution", conversionEvent.ConversionID.String(), conversionEvent.Type)
+		return attributionUncounted, nil
+	}
+
+	windows := s.candidateWindows(ctx, settings)
+	var clickEvents []entity.ClickEvent
+	if suppliedClickID != nil {
+		clickEvents = s.findSuppliedClick(ctx, conversionEvent, *suppliedClickID, settings)
+	}
+
+	if len(clickEvents) == 0 {
+		var err error
+		clickEvents, err = s.findEligibleClicks(ctx, conversionEvent, windows.LookbackWindowHours)
+		if err != nil {
+			return attributionUncounted, fmt.Errorf("failed to find eligible clicks: %w", err)
+		}
+	}
+
+	if s.config.AttributionCrossCampaign && len(clickEvents) > 0 {
+		settings = s.attributionSettings(ctx, clickEvents[len(clickEvents)-1].CampaignID)
+	}
+
+	credits := settings.Model.Attribute(conversionEvent.ConversionDate, clickEvents)
+	primaryCredit := attribution.PrimaryCredit(credits)
+
+	if primaryCredit == nil {
+		if windows.ViewThroughWindowHours > 0 {
+			attributed, err := s.attributeViewThrough(ctx, conversionEvent, settings, windows.ViewThroughWindowHours)
+			if err != nil {
+				return attributionUncounted, err
+			}
+			if attributed && conversionEvent.IsDuplicate {
+				return attributionUncounted, nil
+			}
+			if attributed {
+				return attributionCounted, nil
+			}
+		}
+
+		log.Printf("No matching click event found for conversion %s within %d hour look-back window", conversionEvent.ConversionID.String(), windows.LookbackWindowHours)
+		return attributionUnmatched, nil
+	}
+
+	conversionEvent.ClickID = &primaryCredit.ClickID
+	conversionEvent.CampaignID = primaryCredit.CampaignID
+
+	attributionCredits := s.buildAttributionCredits(conversionEvent, credits, settings.Model.Name())
+	if err := s.conversionEventRepository.Attribute(ctx, conversionEvent, attributionCredits, s.dedupWindow()); err != nil {
+		conversionEvent.ClickID = nil
+		return attributionUncounted, fmt.Errorf("failed to save attribution of conversion %s: %w", conversionEvent.ConversionID.String(), err)
+	}
+
+	if conversionEvent.IsDuplicate {
+		log.Printf("Conversion %s repeats order %s in campaign %s, stored as duplicate",
+			conversionEvent.ConversionID.String(), *conversionEvent.OrderID, conversionEvent.CampaignID.String())
+		return attributionUncounted, nil
+	}
+
+	log.Printf("Attributed conversion %s across %d click(s) using %s model, primary click %s",
+		conversionEvent.ConversionID.String(), len(credits), settings.Model.Name(), primaryCredit.ClickID.String())
+
+	return attributionCounted, nil
+}
+
+// attributionSettings loads the campaign's attribution settings, falling back
+// to the defaults when they cannot be loaded.
+func (s *ConversionEventServiceImpl) attributionSettings(ctx context.Context, campaignID uuid.UUID) *AttributionSettings {
+	settings, err := s.campaignSettingService.GetAttributionSettings(ctx, campaignID)
+	if err != nil {
+		log.Printf("Failed to load attribution settings for campaign %s, using defaults: %v", campaignID.String(), err)
+		return s.campaignSettingService.DefaultAttributionSettings()
+	}
+
+	return settings
+}
+
+// candidateWindows returns the windows to query candidates with. Across
+// campaigns the candidates' campaigns are not known before the query, so the
+// widest windows of any campaign are used and each candidate is checked
+// against its own campaign afterwards.
+func (s *ConversionEventServiceImpl) candidateWindows(ctx context.Context, settings *AttributionSettings) *AttributionWindows {
+	windows := &AttributionWindows{
+		LookbackWindowHours:    settings.LookbackWindowHours,
+		ViewThroughWindowHours: settings.ViewThroughWindowHours,
+	}
+	if !s.config.AttributionCrossCampaign {
+		return windows
+	}
+
+	widestWindows, err := s.campaignSettingService.WidestAttributionWindows(ctx)
+	if err != nil {
+		log.Printf("Failed to load the widest attribution windows, using the conversion's: %v", err)
+		return windows
+	}
+
+	return widestWindows
+}
+
+// inCampaignWindow reports whether a candidate click or impression of the
+// given campaign, made at eventDate, may be credited with the conversion under
+// that campaign's settings.
+func (s *ConversionEventServiceImpl) inCampaignWindow(ctx context.Context, conversionEvent *entity.ConversionEvent, campaignID uuid.UUID, eventDate time.Time, viewThrough bool) bool {
+	settings := s.attributionSettings(ctx, campaignID)
+	if !settings.CountsConversionType(conversionEvent.Type) {
+		return false
+	}
+
+	windowHours := settings.LookbackWindowHours
+	if viewThrough {
+		windowHours = settings.ViewThroughWindowHours
+	}
+
+	return !eventDate.Before(conversionEvent.ConversionDate.Add(-time.Duration(windowHours) * time.Hour))
+}
+
+// attributeViewThrough credits the whole conversion to the most recent
+// impression inside the view-through window. It is only used when no click
+// matched, and reports whether an impression was credited.
+func (s *ConversionEventServiceImpl) attributeViewThrough(ctx context.Context, conversionEvent *entity.ConversionEvent, settings *AttributionSettings, windowHours int) (bool, error) {
+	var impressionEvents []entity.ImpressionEvent
+	var err error
+
+	if s.config.AttributionCrossCampaign {
+		impressionEvents, err = s.impressionEventService.GetImpressionEventsByUserWithinTimeWindow(
+			ctx,
+			conversionEvent.UserID,
+			conversionEvent.ConversionDate,
+			windowHours,
+			s.config.ClickEventMinDelaySeconds,
+		)
+		impressionEvents = s.filterImpressionsByCampaign(ctx, conversionEvent, impressionEvents)
+	} else {
+		impressionEvents, err = s.impressionEventService.GetImpressionEventsByCampaignUserSourceWithinTimeWindow(
+			ctx,
+			conversionEvent.CampaignID,
+			conversionEvent.UserID,
+			conversionEvent.Source,
+			conversionEvent.ConversionDate,
+			settings.ViewThroughWindowHours,
+			s.config.ClickEventMinDelaySeconds,
+		)
+	}
+
+	if err != nil {
+		log.Printf("Error checking for matched impression event: %v", err)
+		return false, nil
+	}
+
+	if len(impressionEvents) == 0 {
+		return false, nil
+	}
+
+	lastImpression := impressionEvents[len(impressionEvents)-1]
+	if s.config.AttributionCrossCampaign {
+		settings = s.attributionSettings(ctx, lastImpression.CampaignID)
+	}
+
+	conversionEvent.ImpressionID = &lastImpression.ImpressionID
+	conversionEvent.CampaignID = lastImpression.CampaignID
+
+	attributionCredit := entity.AttributionCredit{
+		ConversionID:  conversionEvent.ConversionID,
+		ImpressionID:  &lastImpression.ImpressionID,
+		CampaignID:    lastImpression.CampaignID,
+		Model:         settings.Model.Name(),
+		Weight:        decimal.NewFromInt(1),
+		CreditedValue: conversionEvent.Value,
+	}
+	if err := s.conversionEventRepository.Attribute(ctx, conversionEvent, []entity.AttributionCredit{attributionCredit}, s.dedupWindow()); err != nil {
+		conversionEvent.ImpressionID = nil
+		return false, fmt.Errorf("failed to save view-through attribution of conversion %s: %w", conversionEvent.ConversionID.String(), err)
+	}
+
+	if conversionEvent.IsDuplicate {
+		log.Printf("Conversion %s repeats order %s in campaign %s, stored as duplicate",
+			conversionEvent.ConversionID.String(), *conversionEvent.OrderID, conversionEvent.CampaignID.String())
+		return true, nil
+	}
+
+	log.Printf("Attributed conversion %s to impression %s (view-through)", conversionEvent.ConversionID.String(), lastImpression.ImpressionID.String())
+
+	return true, nil
+}
+
+// dedupWindow is how far on either side of a conversion's date an earlier
+// conversion of the same order makes it a duplicate. Zero means no limit.
+func (s *ConversionEventServiceImpl) dedupWindow() time.Duration {
+	return time.Duration(s.config.ConversionDedupWindowHours) * time.Hour
+}
+
+// findSuppliedClick loads the click named by the conversion's click_id so it can
+// be credited directly, skipping the time-window lookup. It returns nil when
+// the click is unknown or could not have led to the conversion, letting the
+// caller fall back to the window lookup.
+func (s *ConversionEventServiceImpl) findSuppliedClick(ctx context.Context, conversionEvent *entity.ConversionEvent, clickID uuid.UUID, settings *AttributionSettings) []entity.ClickEvent {
+	clickEvent, err := s.clickEventService.GetClickEventByID(ctx, clickID)
+	if err != nil {
+		log.Printf("Supplied click %s for conversion %s not found, falling back to window lookup: %v",
+			clickID.String(), conversionEvent.ConversionID.String(), err)
+		return nil
+	}
+
+	if reason := s.rejectSuppliedClick(ctx, conversionEvent, clickEvent, settings); reason != "" {
+		log.Printf("Supplied click %s for conversion %s rejected, %s, falling back to window lookup",
+			clickID.String(), conversionEvent.ConversionID.String(), reason)
+		return nil
+	}
+
+	return []entity.ClickEvent{*clickEvent}
+}
+
+// rejectSuppliedClick returns why a supplied click cannot be credited with the
+// conversion, or an empty string when it can. The click must belong to the
+// converting user and lie inside the look-back window, and without
+// cross-campaign attribution it must share the conversion's campaign.
+func (s *ConversionEventServiceImpl) rejectSuppliedClick(ctx context.Context, conversionEvent *entity.ConversionEvent, clickEvent *entity.ClickEvent, settings *AttributionSettings) string {
+	if clickEvent.UserID != conversionEvent.UserID {
+		return "it belongs to another user"
+	}
+
+	if !s.config.AttributionCrossCampaign && clickEvent.CampaignID != conversionEvent.CampaignID {
+		return "it belongs to another campaign"
+	}
+
+	latest := conversionEvent.ConversionDate.Add(-time.Duration(s.config.ClickEventMinDelaySeconds) * time.Second)
+	if clickEvent.ClickDate.After(latest) {
+		return "it is not before the conversion by the minimum delay"
+	}
+
+	if s.config.AttributionCrossCampaign {
+		if !s.inCampaignWindow(ctx, conversionEvent, clickEvent.CampaignID, clickEvent.ClickDate, false) {
+			return "it is outside its campaign's look-back window"
+		}
+		return ""
+	}
+
+	if clickEvent.ClickDate.Before(conversionEvent.ConversionDate.Add(-time.Duration(settings.LookbackWindowHours) * time.Hour)) {
+		return "it is outside the look-back window"
+	}
+
+	return ""
+}
 
-		if expireErr := s.redisClient.Expire(ctx, counterKey, secondsUntilExpiry); expireErr != nil {
-			log.Printf("Failed to set expiration for Redis key %s: %v", counterKey, expireErr)
+// findEligibleClicks returns the user's clicks across every campaign and source
+// when cross-campaign attribution is enabled, otherwise only the clicks that
+// share the conversion's campaign and source.
+func (s *ConversionEventServiceImpl) findEligibleClicks(ctx context.Context, conversionEvent *entity.ConversionEvent, timeWindowHours int) ([]entity.ClickEvent, error) {
+	if s.config.AttributionCrossCampaign {
+		clickEvents, err := s.clickEventService.GetClickEventsByUserWithinTimeWindow(
+			ctx,
+			conversionEvent.UserID,
+			conversionEvent.ConversionDate,
+			timeWindowHours,
+			s.config.ClickEventMinDelaySeconds,
+		)
+		if err != nil {
+			return nil, err
 		}
+
+		eligible := clickEvents[:0]
+		for _, clickEvent := range clickEvents {
+			if s.inCampaignWindow(ctx, conversionEvent, clickEvent.CampaignID, clickEvent.ClickDate, false) {
+				eligible = append(eligible, clickEvent)
+			}
+		}
+		return eligible, nil
 	}
 
-	log.Printf("Incremented conversion counter for campaign %s on %s: %d", conversionEvent.CampaignID.String(), date, count)
-}
\ No newline at end of file
+	return s.clickEventService.GetClickEventsByCampaignUserSourceWithinTimeWindow(
+		ctx,
+		conversionEvent.CampaignID,
+		conversionEvent.UserID,
+		conversionEvent.Source,
+		conversionEvent.ConversionDate,
+		timeWindowHours,
+		s.config.ClickEventMinDelaySeconds,
+	)
+}
+
+// filterImpressionsByCampaign keeps the impressions inside their own campaign's
+// view-through window.
+func (s *ConversionEventServiceImpl) filterImpressionsByCampaign(ctx context.Context, conversionEvent *entity.ConversionEvent, impressionEvents []entity.ImpressionEvent) []entity.ImpressionEvent {
+	eligible := impressionEvents[:0]
+	for _, impressionEvent := range impressionEvents {
+		if s.inCampaignWindow(ctx, conversionEvent, impressionEvent.CampaignID, impressionEvent.ImpressionDate, true) {
+			eligible = append(eligible, impressionEvent)
+		}
+	}
+
+	return eligible
+}
+
+// countConversion adds an attributed conversion, its value and its user to the
+// counter updates.
+func countConversion(updates *counterUpdates, conversionEvent *entity.ConversionEvent) {
+	updates.increment(counter.NewKey(counter.Conversions, conversionEvent.CampaignID, conversionEvent.ConversionDate), 1)
+	updates.addUser(counter.NewKey(counter.ConversionUsers, conversionEvent.CampaignID, conversionEvent.ConversionDate), conversionEvent.UserID)
+
+	if conversionEvent.Value != nil {
+		updates.increment(counter.NewKey(counter.ConversionValue, conversionEvent.CampaignID, conversionEvent.ConversionDate), counter.Cents(*conversionEvent.Value))
+	}
+}
+
+// buildAttributionCredits converts model credits into rows, splitting the
+// conversion value by weight. Rounding leftovers go to the primary credit so
+// the credited values always add up to the conversion value.
+func (s *ConversionEventServiceImpl) buildAttributionCredits(conversionEvent *entity.ConversionEvent, credits []attribution.Credit, modelName string) []entity.AttributionCredit {
+	primaryCredit := attribution.PrimaryCredit(credits)
+	attributionCredits := make([]entity.AttributionCredit, 0, len(credits))
+	primaryIndex := 0
+	allocated := decimal.Zero
+
+	for _, credit := range credits {
+		clickID := credit.ClickID
+		attributionCredit := entity.AttributionCredit{
+			ConversionID: conversionEvent.ConversionID,
+			ClickID:      &clickID,
+			CampaignID:   credit.CampaignID,
+			Model:        modelName,
+			Weight:       decimal.NewFromFloat(credit.Weight).Round(6),
+		}
+
+		if conversionEvent.Value != nil {
+			creditedValue := conversionEvent.Value.Mul(attributionCredit.Weight).Round(2)
+			attributionCredit.CreditedValue = &creditedValue
+			allocated = allocated.Add(creditedValue)
+		}
+
+		if credit.ClickID == primaryCredit.ClickID {
+			primaryIndex = len(attributionCredits)
+		}
+		attributionCredits = append(attributionCredits, attributionCredit)
+	}
+
+	if conversionEvent.Value != nil {
+		remainder := conversionEvent.Value.Sub(allocated)
+		adjusted := attributionCredits[primaryIndex].CreditedValue.Add(remainder)
+		attributionCredits[primaryIndex].CreditedValue = &adjusted
+	}
+
+	return attributionCredits
+}
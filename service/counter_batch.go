@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"log"
+	"tyrattribution/counter"
+
+	"github.com/google/uuid"
+)
+
+// counterUpdates collects the counter increments and unique users of a batch
+// of events, so they are written together once the events are stored.
+type counterUpdates struct {
+	increments map[counter.Key]int64
+	users      map[counter.Key][]uuid.UUID
+}
+
+func newCounterUpdates() *counterUpdates {
+	return &counterUpdates{
+		increments: make(map[counter.Key]int64),
+		users:      make(map[counter.Key][]uuid.UUID),
+	}
+}
+
+func (u *counterUpdates) increment(key counter.Key, increment int64) {
+	u.increments[key] += increment
+}
+
+func (u *counterUpdates) addUser(key counter.Key, userID uuid.UUID) {
+	u.users[key] = append(u.users[key], userID)
+}
+
+// apply writes the updates. A failure is only logged, since the events behind
+// the updates are already stored and the counters can be rebuilt from them.
+func (u *counterUpdates) apply(ctx context.Context, counterStore counter.Store) {
+	if len(u.increments) > 0 {
+		if err := counterStore.Increment(ctx, u.increments); err != nil {
+			log.Printf("Failed to increment %d Redis counters: %v", len(u.increments), err)
+		} else {
+			log.Printf("Incremented %d Redis counters", len(u.increments))
+		}
+	}
+
+	if len(u.users) > 0 {
+		if err := counterStore.AddUsers(ctx, u.users); err != nil {
+			log.Printf("Failed to add users to %d Redis sketches: %v", len(u.users), err)
+		}
+	}
+}
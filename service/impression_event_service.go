@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+	"time"
+	"tyrattribution/entity"
+
+	"github.com/google/uuid"
+)
+
+type ImpressionEventService interface {
+	CreateImpressionEvent(ctx context.Context, impressionEvent *entity.ImpressionEvent) error
+	GetImpressionCountByCampaign(ctx context.Context, campaignID uuid.UUID, date time.Time) (int64, error)
+	GetImpressionEventsByCampaignUserSourceWithinTimeWindow(ctx context.Context, campaignID uuid.UUID, userID uuid.UUID, source string, conversionDate time.Time, timeWindowHours int, minDelaySeconds int) ([]entity.ImpressionEvent, error)
+	GetImpressionEventsByUserWithinTimeWindow(ctx context.Context, userID uuid.UUID, conversionDate time.Time, timeWindowHours int, minDelaySeconds int) ([]entity.ImpressionEvent, error)
+}
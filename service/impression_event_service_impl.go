@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+	"tyrattribution/counter"
+	"tyrattribution/entity"
+	"tyrattribution/repository"
+
+	"github.com/google/uuid"
+)
+
+type ImpressionEventServiceImpl struct {
+	impressionEventRepository repository.ImpressionEventRepository
+	counterStore              counter.Store
+}
+
+func NewImpressionEventService(impressionEventRepository repository.ImpressionEventRepository, counterStore counter.Store) ImpressionEventService {
+	return &ImpressionEventServiceImpl{
+		impressionEventRepository: impressionEventRepository,
+		counterStore:              counterStore,
+	}
+}
+
+func (s *ImpressionEventServiceImpl) CreateImpressionEvent(ctx context.Context, impressionEvent *entity.ImpressionEvent) error {
+	created, err := s.impressionEventRepository.Create(ctx, impressionEvent)
+	if err != nil {
+		return err
+	}
+
+	if !created {
+		log.Printf("Impression event %s already exists, skipping counter increment", impressionEvent.ImpressionID.String())
+		return nil
+	}
+
+	updates := newCounterUpdates()
+	updates.increment(counter.NewKey(counter.Impressions, impressionEvent.CampaignID, impressionEvent.ImpressionDate), 1)
+	updates.apply(ctx, s.counterStore)
+
+	return nil
+}
+
+func (s *ImpressionEventServiceImpl) GetImpressionCountByCampaign(ctx context.Context, campaignID uuid.UUID, date time.Time) (int64, error) {
+	count, err := s.counterStore.Get(ctx, counter.NewKey(counter.Impressions, campaignID, date))
+	if err != nil {
+		log.Printf("Failed to get impression counter for campaign %s: %v", campaignID.String(), err)
+		return 0, nil
+	}
+
+	return count, nil
+}
+
+func (s *ImpressionEventServiceImpl) GetImpressionEventsByCampaignUserSourceWithinTimeWindow(ctx context.Context, campaignID uuid.UUID, userID uuid.UUID, source string, conversionDate time.Time, timeWindowHours int, minDelaySeconds int) ([]entity.ImpressionEvent, error) {
+	return s.impressionEventRepository.GetImpressionEventsByCampaignUserSourceWithinTimeWindow(ctx, campaignID, userID, source, conversionDate, timeWindowHours, minDelaySeconds)
+}
+
+func (s *ImpressionEventServiceImpl) GetImpressionEventsByUserWithinTimeWindow(ctx context.Context, userID uuid.UUID, conversionDate time.Time, timeWindowHours int, minDelaySeconds int) ([]entity.ImpressionEvent, error) {
+	return s.impressionEventRepository.GetImpressionEventsByUserWithinTimeWindow(ctx, userID, conversionDate, timeWindowHours, minDelaySeconds)
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReconciliationService interface {
+	Reconcile(ctx context.Context, from time.Time, to time.Time, repair bool) (*ReconciliationReport, error)
+}
+
+type ReconciliationReport struct {
+	From          string        `json:"from"`
+	To            string        `json:"to"`
+	Repair        bool          `json:"repair"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Discrepancy is a metric whose stored value differs from the value recomputed
+// from the event tables. Store is redis or campaign_journal, and Missing marks
+// a journal row that does not exist.
+type Discrepancy struct {
+	Date       string    `json:"date"`
+	CampaignID uuid.UUID `json:"campaign_id"`
+	Store      string    `json:"store"`
+	Metric     string    `json:"metric"`
+	Expected   int64     `json:"expected"`
+	Actual     int64     `json:"actual"`
+	Missing    bool      `json:"missing,omitempty"`
+	Repaired   bool      `json:"repaired"`
+}
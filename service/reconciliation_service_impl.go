@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"tyrattribution/counter"
+	"tyrattribution/entity"
+	"tyrattribution/repository"
+
+	"github.com/google/uuid"
+)
+
+// maxReconciliationDays caps the date range of one reconciliation, since every
+// day is recounted from the event tables.
+const maxReconciliationDays = 92
+
+const (
+	reconciliationStoreRedis   = "redis"
+	reconciliationStoreJournal = "campaign_journal"
+)
+
+type ReconciliationServiceImpl struct {
+	campaignJournalRepo    repository.CampaignJournalRepository
+	campaignJournalService CampaignJournalService
+	counterStore           counter.Store
+}
+
+func NewReconciliationService(
+	campaignJournalRepo repository.CampaignJournalRepository,
+	campaignJournalService CampaignJournalService,
+	counterStore counter.Store,
+) ReconciliationService {
+	return &ReconciliationServiceImpl{
+		campaignJournalRepo:    campaignJournalRepo,
+		campaignJournalService: campaignJournalService,
+		counterStore:           counterStore,
+	}
+}
+
+// Reconcile recomputes each day's impressions, clicks, attributed conversions
+// and conversion value from the event tables and compares them with the Redis
+// counters, while they are live, and with the journal rows of days before
+// today. With repair set, the stored values are overwritten with the
+// recomputed ones. Today's counters are only reported, because events still in
+// flight would be counted twice by an overwrite.
+func (s *ReconciliationServiceImpl) Reconcile(ctx context.Context, from time.Time, to time.Time, repair bool) (*ReconciliationReport, error) {
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	to = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, to.Location())
+
+	if to.Before(from) {
+		return nil, fmt.Errorf("to date %s is before from date %s", to.Format("2006-01-02"), from.Format("2006-01-02"))
+	}
+	if to.Sub(from) >= maxReconciliationDays*24*time.Hour {
+		return nil, fmt.Errorf("date range is longer than %d days", maxReconciliationDays)
+	}
+
+	report := &ReconciliationReport{
+		From:          from.Format("2006-01-02"),
+		To:            to.Format("2006-01-02"),
+		Repair:        repair,
+		Discrepancies: []Discrepancy{},
+	}
+
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		discrepancies, err := s.reconcileDay(ctx, date, repair)
+		if err != nil {
+			return nil, err
+		}
+		report.Discrepancies = append(report.Discrepancies, discrepancies...)
+	}
+
+	log.Printf("Reconciled %s to %s: %d discrepancies found", report.From, report.To, len(report.Discrepancies))
+	return report, nil
+}
+
+func (s *ReconciliationServiceImpl) reconcileDay(ctx context.Context, date time.Time, repair bool) ([]Discrepancy, error) {
+	dateStr := date.Format("2006-01-02")
+	now := time.Now()
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	counts, err := s.campaignJournalRepo.CountDailyEvents(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count events for %s: %w", dateStr, err)
+	}
+
+	expected := make(map[uuid.UUID]repository.DailyEventCounts, len(counts))
+	for _, count := range counts {
+		expected[count.CampaignID] = count
+	}
+
+	var discrepancies []Discrepancy
+
+	if counter.IsLive(date, now) {
+		redisDiscrepancies, err := s.reconcileCounters(ctx, date, expected, repair && date.Before(startOfToday))
+		if err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, redisDiscrepancies...)
+	}
+
+	if date.Before(startOfToday) {
+		journalDiscrepancies, err := s.reconcileJournal(ctx, date, expected, repair)
+		if err != nil {
+			return nil, err
+		}
+		discrepancies = append(discrepancies, journalDiscrepancies...)
+	}
+
+	return discrepancies, nil
+}
+
+// reconcileCounters compares the day's Redis counters of every campaign with
+// events that day. Counters of campaigns without events are not found, since
+// Redis is not scanned. The comparison is skipped while the counters are
+// unavailable.
+func (s *ReconciliationServiceImpl) reconcileCounters(ctx context.Context, date time.Time, expected map[uuid.UUID]repository.DailyEventCounts, repair bool) ([]Discrepancy, error) {
+	var discrepancies []Discrepancy
+	repairs := make(map[uuid.UUID]counter.Counts)
+
+	for campaignID, count := range expected {
+		actual, err := s.counterStore.GetDay(ctx, campaignID, date)
+		if errors.Is(err, counter.ErrUnavailable) {
+			log.Printf("Skipping Redis reconciliation for %s: %v", date.Format("2006-01-02"), err)
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get Redis counters for campaign %s: %w", campaignID.String(), err)
+		}
+
+		found := compareMetrics(date, campaignID, reconciliationStoreRedis, count, actual.Impressions, actual.Clicks, actual.Conversions, actual.ConversionValueCents)
+		if len(found) == 0 {
+			continue
+		}
+
+		discrepancies = append(discrepancies, found...)
+		repairs[campaignID] = counter.Counts{
+			Impressions:          count.Impressions,
+			Clicks:               count.Clicks,
+			Conversions:          count.Conversions,
+			ConversionValueCents: counter.Cents(count.ConversionValue),
+		}
+	}
+
+	if repair && len(repairs) > 0 {
+		if err := s.counterStore.SetDay(ctx, date, repairs); err != nil {
+			return nil, fmt.Errorf("failed to repair Redis counters for %s: %w", date.Format("2006-01-02"), err)
+		}
+		markRepaired(discrepancies)
+	}
+
+	return discrepancies, nil
+}
+
+// reconcileJournal compares the day's journal rows with the recomputed counts.
+// A campaign with events but no row is reported as missing, and a row of a
+// campaign without events is expected to hold zeros.
+func (s *ReconciliationServiceImpl) reconcileJournal(ctx context.Context, date time.Time, expected map[uuid.UUID]repository.DailyEventCounts, repair bool) ([]Discrepancy, error) {
+	dateStr := date.Format("2006-01-02")
+
+	campaignJournals, err := s.campaignJournalRepo.GetByDate(ctx, date)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign journals for %s: %w", dateStr, err)
+	}
+
+	journals := make(map[uuid.UUID]*entity.CampaignJournal, len(campaignJournals))
+	for i := range campaignJournals {
+		journals[campaignJournals[i].CampaignID] = &campaignJournals[i]
+	}
+
+	campaignIDs := make([]uuid.UUID, 0, len(expected)+len(journals))
+	for campaignID := range expected {
+		campaignIDs = append(campaignIDs, campaignID)
+	}
+	for campaignID := range journals {
+		if _, ok := expected[campaignID]; !ok {
+			campaignIDs = append(campaignIDs, campaignID)
+		}
+	}
+
+	var discrepancies []Discrepancy
+	for _, campaignID := range campaignIDs {
+		count, ok := expected[campaignID]
+		if !ok {
+			count = repository.DailyEventCounts{CampaignID: campaignID}
+		}
+
+		var found []Discrepancy
+		campaignJournal, exists := journals[campaignID]
+		if exists {
+			found = compareMetrics(date, campaignID, reconciliationStoreJournal, count,
+				valueOrZero(campaignJournal.NumberOfImpression),
+				valueOrZero(campaignJournal.NumberOfClick),
+				valueOrZero(campaignJournal.NumberOfConversion),
+				journalValueCents(campaignJournal))
+		} else {
+			found = compareMetrics(date, campaignID, reconciliationStoreJournal, count, 0, 0, 0, 0)
+			for i := range found {
+				found[i].Missing = true
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+
+		if repair {
+			if err := s.campaignJournalService.SaveDailyCounts(ctx, date, count); err != nil {
+				return nil, fmt.Errorf("failed to repair campaign journal of campaign %s for %s: %w", campaignID.String(), dateStr, err)
+			}
+			markRepaired(found)
+		}
+
+		discrepancies = append(discrepancies, found...)
+	}
+
+	return discrepancies, nil
+}
+
+func compareMetrics(date time.Time, campaignID uuid.UUID, store string, expected repository.DailyEventCounts, impressions int64, clicks int64, conversions int64, conversionValueCents int64) []Discrepancy {
+	metrics := []struct {
+		name     string
+		expected int64
+		actual   int64
+	}{
+		{"impressions", expected.Impressions, impressions},
+		{"clicks", expected.Clicks, clicks},
+		{"conversions", expected.Conversions, conversions},
+		{"conversion_value_cents", counter.Cents(expected.ConversionValue), conversionValueCents},
+	}
+
+	var discrepancies []Discrepancy
+	for _, metric := range metrics {
+		if metric.expected == metric.actual {
+			continue
+		}
+
+		discrepancies = append(discrepancies, Discrepancy{
+			Date:       date.Format("2006-01-02"),
+			CampaignID: campaignID,
+			Store:      store,
+			Metric:     metric.name,
+			Expected:   metric.expected,
+			Actual:     metric.actual,
+		})
+	}
+
+	return discrepancies
+}
+
+func markRepaired(discrepancies []Discrepancy) {
+	for i := range discrepancies {
+		discrepancies[i].Repaired = true
+	}
+}
+
+func valueOrZero(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func journalValueCents(campaignJournal *entity.CampaignJournal) int64 {
+	if campaignJournal.TotalConversionValue == nil {
+		return 0
+	}
+	return counter.Cents(*campaignJournal.TotalConversionValue)
+}
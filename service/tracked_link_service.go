@@ -0,0 +1,17 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"tyrattribution/entity"
+)
+
+var (
+	ErrTrackedLinkNotFound = errors.New("tracked link not found")
+	ErrTrackedLinkExists   = errors.New("tracked link already exists")
+)
+
+type TrackedLinkService interface {
+	CreateTrackedLink(ctx context.Context, trackedLink *entity.TrackedLink) error
+	GetTrackedLink(ctx context.Context, linkID string) (*entity.TrackedLink, error)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"tyrattribution/entity"
+	"tyrattribution/repository"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const trackedLinkIDLength = 12
+
+type TrackedLinkServiceImpl struct {
+	trackedLinkRepository repository.TrackedLinkRepository
+}
+
+func NewTrackedLinkService(trackedLinkRepository repository.TrackedLinkRepository) TrackedLinkService {
+	return &TrackedLinkServiceImpl{
+		trackedLinkRepository: trackedLinkRepository,
+	}
+}
+
+func (s *TrackedLinkServiceImpl) CreateTrackedLink(ctx context.Context, trackedLink *entity.TrackedLink) error {
+	if trackedLink.LinkID == "" {
+		trackedLink.LinkID = strings.ReplaceAll(uuid.New().String(), "-", "")[:trackedLinkIDLength]
+	}
+
+	err := s.trackedLinkRepository.Create(ctx, trackedLink)
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrTrackedLinkExists
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Created tracked link %s for campaign %s", trackedLink.LinkID, trackedLink.CampaignID.String())
+	return nil
+}
+
+func (s *TrackedLinkServiceImpl) GetTrackedLink(ctx context.Context, linkID string) (*entity.TrackedLink, error) {
+	trackedLink, err := s.trackedLinkRepository.GetByID(ctx, linkID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTrackedLinkNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return trackedLink, nil
+}